@@ -0,0 +1,192 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/spf13/cobra"
+)
+
+// aliasCmd represents the alias command
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage short names for profiles",
+	Long: `Alias manages short names for profiles, stored in the wasp config file.
+Aliases can be used anywhere wasp takes a profile name:
+
+  wasp alias add prod "Acme Production_AdministratorAccess"
+  eval $(wasp switch prod)
+
+With --materialize the alias is also written to the AWS config file as a
+profile that signs in the same way, with the same settings such as region,
+so the AWS CLI understands it too.`,
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List aliases",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		for name := range wc.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ALIAS\tPROFILE\tMATERIALIZED\tSTATUS")
+		for _, name := range names {
+			target := wc.Aliases[name]
+			materialized := "no"
+			if p, err := cf.GetProfile(name); err == nil && p.AliasFor == target {
+				materialized = "yes"
+			}
+			status := "ok"
+			if !cf.HasProfile(target) {
+				status = "missing profile"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, target, materialized, status)
+		}
+		return w.Flush()
+	},
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <alias> <profile>",
+	Short: "Add or change an alias",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		materialize, _ := cmd.Flags().GetBool("materialize")
+		alias, target := args[0], args[1]
+
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		profile, err := cf.GetProfile(target)
+		if err != nil {
+			return err
+		}
+		if existing, err := cf.GetProfile(alias); err == nil && existing.AliasFor == "" {
+			return fmt.Errorf("alias %s would hide the profile of the same name", alias)
+		}
+		if wc.Aliases == nil {
+			wc.Aliases = make(map[string]string)
+		}
+		wc.Aliases[alias] = target
+		if err := wc.Save(); err != nil {
+			return err
+		}
+
+		if materialize {
+			materializeAlias(cf, alias, profile)
+			if err := saveConfigFile(cf); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:     "rm <alias>",
+	Aliases: []string{"remove"},
+	Short:   "Remove an alias",
+	Long: `Remove deletes an alias from the wasp config file, along with its profile in
+the AWS config file if it was materialized.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		alias := args[0]
+
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		target, ok := wc.Aliases[alias]
+		if !ok {
+			return fmt.Errorf("alias %s not found", alias)
+		}
+		delete(wc.Aliases, alias)
+		if err := wc.Save(); err != nil {
+			return err
+		}
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		if p, err := cf.GetProfile(alias); err == nil && p.AliasFor == target {
+			cf.DeleteProfile(alias)
+			return saveConfigFile(cf)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(aliasCmd)
+	aliasCmd.AddCommand(aliasListCmd, aliasAddCmd, aliasRemoveCmd)
+
+	aliasAddCmd.Flags().Bool("materialize", false, "also write the alias to the AWS config file as a profile")
+}
+
+// materializeAlias writes an alias as a profile that signs in like the
+// target, with the target's other keys such as region, marked so wasp can
+// tell it apart from hand-written profiles. Keys copied from an earlier
+// target are removed.
+func materializeAlias(cf *awsconfig.ConfigFile, alias string, target *awsconfig.Profile) {
+	p := cf.Profile(alias)
+	p.Managed = true
+	p.Generated = true
+	p.NoDefaults = true
+	p.SSOSession = target.SSOSession
+	p.AccountID = target.AccountID
+	p.AccountName = target.AccountName
+	p.AccountEmail = target.AccountEmail
+	p.RoleName = target.RoleName
+	p.RoleARN = target.RoleARN
+	p.SourceProfile = target.SourceProfile
+	p.CredentialSource = target.CredentialSource
+	p.Services = target.Services
+	p.Settings = cf.ProfileSettings(target.Name)
+	p.AliasFor = target.Name
+}
+
+// resolveProfile returns the profile a name refers to. Profile names take
+// precedence over aliases.
+func resolveProfile(cf *awsconfig.ConfigFile, wc *waspconfig.Config, name string) (*awsconfig.Profile, error) {
+	if p, err := cf.GetProfile(name); err == nil {
+		return p, nil
+	}
+	target, ok := wc.Aliases[name]
+	if !ok {
+		return nil, fmt.Errorf("profile or alias %s not found", name)
+	}
+	p, err := cf.GetProfile(target)
+	if err != nil {
+		return nil, fmt.Errorf("alias %s refers to missing profile %s", name, target)
+	}
+	return p, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/spf13/cobra"
+)
+
+// composeCmd represents the compose command
+var composeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Rebuild the AWS config file from its source files",
+	Long: `Compose rebuilds the AWS config file from the files listed in the files
+section of the wasp config: the hand-written files.sources followed by
+files.managed, the file wasp writes generated profiles to.
+
+  files:
+    managed: ~/.aws/config.d/wasp
+    sources:
+      - ~/.aws/config.d/personal
+    compose: ~/.aws/config
+
+Sync, init and alias compose again after every change; run compose after
+editing a source file. If files.compose isn't the default AWS config file,
+point the AWS CLI and SDKs at it with AWS_CONFIG_FILE.
+
+The composed file is only ever written by compose. An existing AWS config
+file wasp didn't compose is left alone: --migrate moves it to the first
+files.sources entry, which mustn't exist yet, and composes. Commands like
+aws configure write to the composed file; compose notices and stops
+rather than lose those edits. Move them into a source file, then run
+compose --force.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		migrate, _ := cmd.Flags().GetBool("migrate")
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		if err := wc.Validate(); err != nil {
+			return err
+		}
+		if wc.Files.Managed == "" {
+			return errors.New("files.managed isn't set in the wasp config, so wasp writes to the AWS config file directly")
+		}
+
+		path, sources := composeFiles(wc)
+		if migrate {
+			if err := migrateConfigFile(wc, path); err != nil {
+				return err
+			}
+		}
+		if force {
+			err = awsconfig.ForceCompose(path, sources...)
+		} else {
+			err = awsconfig.Compose(path, sources...)
+		}
+		switch {
+		case errors.Is(err, awsconfig.ErrNotComposed):
+			return fmt.Errorf("%w, or run wasp compose --migrate", err)
+		case errors.Is(err, awsconfig.ErrComposedChanged):
+			return fmt.Errorf("%w, then run wasp compose --force", err)
+		case err != nil:
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Composed", path)
+		if path != config.DefaultSharedConfigFilename() && os.Getenv("AWS_CONFIG_FILE") != path {
+			fmt.Fprintf(os.Stderr, "Point the AWS CLI and SDKs at it with:\n  export AWS_CONFIG_FILE=%s\n", path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(composeCmd)
+
+	composeCmd.Flags().Bool("force", false, "overwrite the composed file even if it was edited since wasp composed it")
+	composeCmd.Flags().Bool("migrate", false, "move an AWS config file wasp didn't compose to the first files.sources entry")
+}
+
+// migrateConfigFile moves a hand-written AWS config file to the first
+// source file so it can be composed. A file wasp composed is left alone.
+func migrateConfigFile(wc *waspconfig.Config, path string) error {
+	sources := wc.Files.SourcePaths()
+	if len(sources) == 0 {
+		return errors.New("add a files.sources entry to the wasp config to move the AWS config file to")
+	}
+	if err := awsconfig.CheckCompose(path, append(sources, wc.Files.ManagedPath())...); !errors.Is(err, awsconfig.ErrNotComposed) {
+		return nil
+	}
+	if _, err := os.Stat(sources[0]); err == nil {
+		return fmt.Errorf("%s already exists; move the profiles in %s into it by hand", sources[0], path)
+	}
+	if err := os.MkdirAll(filepath.Dir(sources[0]), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, sources[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Moved %s to %s\n", path, sources[0])
+	return nil
+}
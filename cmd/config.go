@@ -0,0 +1,231 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/buzzsurfr/wasp/internal/prompt"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and change the wasp configuration",
+	Long: `Config views and changes the wasp config file, ~/.wasp/config.yaml unless
+--config says otherwise. Keys are dotted paths into the file:
+
+  version              schema version, currently 1
+  sessions.N.name      SSO sessions to sync (all of them if none are listed)
+  sessions.N.filters   include and exclude rules for accounts and roles
+  naming.template      Go template for profile names, e.g.
+                       "{{.AccountName}}_{{.RoleName}}"; can use .Session,
+                       .SSORegion, .AccountID, .AccountName, .AccountEmail
+                       and .RoleName
+  naming.collisions    suffix-account-id (default), prefix-session or fail
+  defaults.KEY         keys written to every generated profile, e.g. region
+  rules.N              keys (set) and tags (tags) for profiles matching
+                       session, account, account_id, email or role patterns;
+                       switch and exec ask before using prod and sensitive
+                       profiles
+  chains.N             profiles assuming a role (assume) from the SSO
+                       profiles matching session, account, account_id,
+                       email or role patterns, named by a template (name);
+                       a role name is looked up in the matched account, in
+                       the partition of the session's sso_region
+  ui.tree              start switch and init in the tree view
+  ui.height            rows shown by the pickers
+  ui.tag_colors.TAG    color of profiles with a tag, an ANSI 256 color
+                       number or #rrggbb (prod is 196, sensitive 208)
+  aliases.NAME         short names for profiles
+  manifest_aliases     aliases wasp apply set, by manifest, so it can
+                       remove the ones a manifest drops
+  files.managed        file wasp writes generated profiles to
+  files.sources        hand-written AWS config files
+  files.compose        file wasp compose builds (default ~/.aws/config)
+  kube.clusters        EKS cluster inventory (default ~/.wasp/clusters.yaml)
+  kube.kubeconfig      file wasp kube sync writes (default ~/.kube/config)
+  kube.context         Go template for kubeconfig context names, e.g.
+                       "{{.Profile}}@{{.Cluster}}"; can also use .Region and
+                       the naming fields
+  prompt.format        Go template for wasp prompt, e.g.
+                       "{{.Name}}{{with .Remaining}} {{.}}{{end}}"; can use
+                       .Profile, .Alias, .AccountID, .AccountName, .Role,
+                       .Session, .Tags, .Color and .ExpiresAt`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a config value, or the whole config",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return yaml.NewEncoder(os.Stdout).Encode(wc)
+		}
+		v, err := wc.Get(args[0])
+		if err != nil {
+			return err
+		}
+		switch v.(type) {
+		case map[string]any, []any:
+			return yaml.NewEncoder(os.Stdout).Encode(v)
+		default:
+			fmt.Println(v)
+		}
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a config value",
+	Long: `Set changes a config value. Values are read as YAML, so true and 10 are a
+boolean and a number. An empty value removes the key:
+
+  wasp config set naming.template "{{.Session}}-{{.AccountName}}-{{.RoleName}}"
+  wasp config set defaults.region eu-west-1
+  wasp config set sessions.0.name corp
+  wasp config set defaults.output ""`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		if err := wc.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		if err := checkPromptFormat(wc); err != nil {
+			return err
+		}
+		return wc.Save()
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in your editor",
+	Long: `Edit opens the config file in $VISUAL or $EDITOR (vi if neither is set)
+and validates it when the editor exits.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		if !wc.Exists() {
+			if err := wc.Save(); err != nil {
+				return err
+			}
+		}
+
+		for {
+			editor := exec.Command("sh", "-c", editorCommand()+` "$1"`, "sh", wc.Path())
+			editor.Stdin, editor.Stdout, editor.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := editor.Run(); err != nil {
+				return fmt.Errorf("running editor: %w", err)
+			}
+
+			wc, err = waspconfig.Load(wc.Path())
+			if err == nil {
+				err = wc.Validate()
+			}
+			if err == nil {
+				err = checkPromptFormat(wc)
+			}
+			if err == nil {
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, err)
+			if !confirm("Edit again?") {
+				return errors.New("config file left invalid")
+			}
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for mistakes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		if err := wc.Validate(); err != nil {
+			return err
+		}
+		if err := checkPromptFormat(wc); err != nil {
+			return err
+		}
+		for _, key := range wc.UnknownKeys() {
+			fmt.Fprintf(os.Stderr, "Warning: %s is not a setting this wasp knows; it's kept as it is\n", key)
+		}
+
+		// Sessions are named in the AWS config file, so check they're there
+		if cf, err := loadConfigFile(); err == nil {
+			for _, s := range wc.Sessions {
+				if !cf.HasSSOSession(s.Name) {
+					fmt.Fprintf(os.Stderr, "Warning: SSO session %s is not in the AWS config file\n", s.Name)
+				}
+			}
+		}
+
+		if !wc.Exists() {
+			fmt.Printf("%s doesn't exist; using defaults\n", wc.Path())
+		} else {
+			fmt.Printf("%s is valid\n", wc.Path())
+		}
+		return nil
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the config file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := waspConfigPath()
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configEditCmd, configValidateCmd, configPathCmd)
+}
+
+// editorCommand returns the user's preferred editor
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(env); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// checkPromptFormat reports a prompt.format wasp prompt can't use the way
+// Validate reports the rest of the config
+func checkPromptFormat(wc *waspconfig.Config) error {
+	if err := prompt.Check(wc.Prompt.Format); err != nil {
+		return &waspconfig.ValidationError{Path: wc.Path(), Problems: []string{fmt.Sprintf("prompt.format: %v", err)}}
+	}
+	return nil
+}
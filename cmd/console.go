@@ -0,0 +1,114 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/buzzsurfr/wasp/internal/console"
+	"github.com/spf13/cobra"
+)
+
+// consoleCmd represents the console command
+var consoleCmd = &cobra.Command{
+	Use:   "console [profile|alias]",
+	Short: "Sign in to the AWS console with a profile",
+	Long: `Console signs in to the AWS console with a profile's role credentials and
+prints the sign-in URL, or opens it in a browser with --open. Without a
+profile it uses AWS_PROFILE.
+
+--service picks the console page to land on, e.g. ec2, s3/buckets or a full
+console URL, and --region the region to show it in (the profile's region
+by default). WASP_FEDERATION_ENDPOINT points wasp at another federation
+endpoint, such as the one wasp dev sso-emulator serves.
+
+  wasp console prod --service cloudwatch --open`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, _ := cmd.Flags().GetString("service")
+		region, _ := cmd.Flags().GetString("region")
+		open, _ := cmd.Flags().GetBool("open")
+
+		name := os.Getenv("AWS_PROFILE")
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			return errors.New("no profile given and AWS_PROFILE isn't set")
+		}
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		profile, err := resolveProfile(cf, wc, name)
+		if err != nil {
+			return err
+		}
+		if region == "" {
+			region = cf.ProfileKeys(profile.Name)["region"]
+		}
+
+		ctx := context.Background()
+		creds, err := profileCredentials(ctx, cf, profile)
+		if err != nil {
+			return err
+		}
+
+		partition := console.PartitionFor(region)
+		client := &console.Client{
+			HTTP:     &http.Client{Timeout: 30 * time.Second},
+			Endpoint: partition.Endpoint,
+		}
+		if endpoint := os.Getenv("WASP_FEDERATION_ENDPOINT"); endpoint != "" {
+			client.Endpoint = endpoint
+		}
+		url, err := client.URL(ctx, creds, partition.Destination(service, region))
+		if err != nil {
+			return err
+		}
+
+		if open {
+			if err := openBrowser(url); err == nil {
+				fmt.Fprintf(os.Stderr, "Opened the AWS console for %s\n", profile.Name)
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, "Unable to open a browser; open this URL instead:")
+		}
+		fmt.Println(url)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(consoleCmd)
+
+	consoleCmd.Flags().StringP("service", "s", "", "console page to open, e.g. ec2 or s3/buckets")
+	consoleCmd.Flags().StringP("region", "r", "", "region to show (default the profile's region)")
+	consoleCmd.Flags().Bool("open", false, "open the URL in a browser instead of printing it")
+}
+
+// openBrowser opens a URL with the desktop's default browser
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
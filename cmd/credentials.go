@@ -0,0 +1,176 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sso/types"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+	"github.com/spf13/cobra"
+)
+
+// credentialRefreshWindow is how long before expiry cached role
+// credentials are replaced
+const credentialRefreshWindow = 5 * time.Minute
+
+// credentialProcessCmd represents the credential-process command
+var credentialProcessCmd = &cobra.Command{
+	Use:   "credential-process <profile|alias>",
+	Short: "Print credentials for the AWS CLI credential_process setting",
+	Long: `Credential process prints temporary credentials for a profile or alias in
+the format the AWS CLI and SDKs expect from a credential_process:
+
+  [profile prod-tools]
+  credential_process = wasp credential-process prod
+
+Role credentials for SSO profiles are cached per SSO session until shortly
+before they expire; wasp logout deletes them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		profile, err := resolveProfile(cf, wc, args[0])
+		if err != nil {
+			return err
+		}
+
+		creds, err := profileCredentials(context.Background(), cf, profile)
+		if err != nil {
+			return err
+		}
+
+		out := struct {
+			Version         int
+			AccessKeyID     string `json:"AccessKeyId"`
+			SecretAccessKey string
+			SessionToken    string `json:",omitempty"`
+			Expiration      string `json:",omitempty"`
+		}{
+			Version:         1,
+			AccessKeyID:     creds.AccessKeyID,
+			SecretAccessKey: creds.SecretAccessKey,
+			SessionToken:    creds.SessionToken,
+		}
+		if creds.CanExpire {
+			out.Expiration = creds.Expires.UTC().Format(time.RFC3339)
+		}
+		return json.NewEncoder(os.Stdout).Encode(out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(credentialProcessCmd)
+}
+
+// profileCredentials returns credentials for a profile. SSO profiles go
+// through wasp's token handling and credential cache; anything else is
+// resolved by the AWS SDK.
+func profileCredentials(ctx context.Context, cf *awsconfig.ConfigFile, profile *awsconfig.Profile) (aws.Credentials, error) {
+	if profile.SSOSession == "" {
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profile.Name))
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+		return cfg.Credentials.Retrieve(ctx)
+	}
+
+	session, err := cf.GetSSOSession(profile.SSOSession)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	cachePath, err := credentialCachePath(session.Name, profile.AccountID, profile.RoleName)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	if creds, err := loadCachedCredentials(cachePath); err == nil && time.Now().Add(credentialRefreshWindow).Before(creds.Expires) {
+		return creds, nil
+	}
+
+	clients, err := newSSOClients(ctx, session)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	token, err := clients.accessToken(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	input := &sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(token.AccessToken),
+		AccountId:   aws.String(profile.AccountID),
+		RoleName:    aws.String(profile.RoleName),
+	}
+	out, err := clients.sso.GetRoleCredentials(ctx, input)
+	var aerr *types.UnauthorizedException
+	if errors.As(err, &aerr) {
+		if token, err = clients.login(); err != nil {
+			return aws.Credentials{}, err
+		}
+		input.AccessToken = aws.String(token.AccessToken)
+		out, err = clients.sso.GetRoleCredentials(ctx, input)
+	}
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("getting role credentials for %s: %w", profile.Name, err)
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     aws.ToString(out.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(out.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.ToString(out.RoleCredentials.SessionToken),
+		Source:          "wasp",
+		CanExpire:       true,
+		Expires:         time.UnixMilli(out.RoleCredentials.Expiration),
+	}
+	if err := saveCachedCredentials(cachePath, creds); err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to cache credentials:", err)
+	}
+	return creds, nil
+}
+
+// credentialCachePath returns where role credentials are cached. They live
+// in the session's cache directory so logging out removes them.
+func credentialCachePath(session, accountID, roleName string) (string, error) {
+	dir, err := waspdir.SessionCacheDir(session)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials", accountID+"_"+roleName+".json"), nil
+}
+
+func loadCachedCredentials(path string) (aws.Credentials, error) {
+	var creds aws.Credentials
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return creds, err
+	}
+	err = json.Unmarshal(data, &creds)
+	return creds, err
+}
+
+func saveCachedCredentials(path string, creds aws.Credentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/buzzsurfr/wasp/internal/ssoemulator"
+	"github.com/spf13/cobra"
+)
+
+// devCmd groups commands that are only useful when developing wasp itself
+var devCmd = &cobra.Command{
+	Use:    "dev",
+	Short:  "Development and testing helpers",
+	Hidden: true,
+}
+
+// ssoEmulatorCmd represents the dev sso-emulator command
+var ssoEmulatorCmd = &cobra.Command{
+	Use:   "sso-emulator",
+	Short: "Run a local AWS SSO portal and OIDC emulator",
+	Long: `SSO emulator starts an HTTP server that speaks the AWS SSO portal and
+SSO OIDC protocols, serving the accounts and roles from a YAML fixture.
+Device logins are approved immediately, so login, token refresh, sync and
+console sign-in can be exercised without AWS access.
+
+Point the AWS SDK (and wasp) at it with the printed environment variables:
+
+  eval $(wasp dev sso-emulator --fixture accounts.yaml)`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fixturePath, _ := cmd.Flags().GetString("fixture")
+		addr, _ := cmd.Flags().GetString("addr")
+
+		fixture, err := ssoemulator.LoadFixture(fixturePath)
+		if err != nil {
+			return err
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		endpoint := "http://" + listener.Addr().String()
+
+		fmt.Printf("export AWS_ENDPOINT_URL_SSO=%s\n", endpoint)
+		fmt.Printf("export AWS_ENDPOINT_URL_SSO_OIDC=%s\n", endpoint)
+		fmt.Printf("export WASP_FEDERATION_ENDPOINT=%s/federation\n", endpoint)
+		fmt.Fprintf(os.Stderr, "SSO emulator listening on %s with %d accounts\n", endpoint, len(fixture.Accounts))
+		fmt.Fprintf(os.Stderr, "\n[sso-session emulator]\nsso_start_url = %s\nsso_region = %s\n\n", fixture.StartURL, fixture.Region)
+
+		return http.Serve(listener, ssoemulator.New(fixture))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+	devCmd.AddCommand(ssoEmulatorCmd)
+
+	ssoEmulatorCmd.Flags().String("fixture", "", "YAML fixture of accounts and roles")
+	ssoEmulatorCmd.Flags().String("addr", "127.0.0.1:0", "address to listen on")
+	ssoEmulatorCmd.MarkFlagRequired("fixture")
+}
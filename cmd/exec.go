@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec <profile|alias> -- <command> [args...]",
+	Short: "Run a command with an AWS profile",
+	Long: `Exec runs a command with AWS_PROFILE set to a profile or alias, without
+changing the profile of the current shell:
+
+  wasp exec prod -- aws s3 ls
+
+Everything after the profile goes to the command, flags included, so the --
+is optional. Like switch, exec asks for the account name of prod and
+sensitive profiles first, unless --confirm gives it.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		profile, err := resolveProfile(cf, wc, args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := confirmProfile(cmd, cf, wc, profile); err != nil {
+			return err
+		}
+
+		command := args[1:]
+		if command[0] == "--" {
+			command = command[1:]
+		}
+		if len(command) == 0 {
+			return errors.New("no command to run")
+		}
+		child := exec.Command(command[0], command[1:]...)
+		child.Stdin = os.Stdin
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+		child.Env = profileEnv(os.Environ(), profile.Name)
+		for _, env := range tagEnv(wc, profileTags(cf, wc, profile)) {
+			if env[1] != "" {
+				child.Env = append(child.Env, env[0]+"="+env[1])
+			}
+		}
+
+		err = child.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// Flags after the profile belong to the command
+	execCmd.Flags().SetInterspersed(false)
+
+	execCmd.Flags().String("confirm", "", "account name of a prod or sensitive profile, instead of typing it")
+}
+
+// profileEnv returns env with AWS_PROFILE set to profile. Variables that
+// would take precedence over the profile, like static credentials, are
+// removed, as are the previous profile's tags.
+func profileEnv(env []string, profile string) []string {
+	overrides := map[string]bool{
+		"AWS_PROFILE":           true,
+		"AWS_DEFAULT_PROFILE":   true,
+		"AWS_ACCESS_KEY_ID":     true,
+		"AWS_SECRET_ACCESS_KEY": true,
+		"AWS_SESSION_TOKEN":     true,
+		"WASP_PROFILE_TAGS":     true,
+		"WASP_PROMPT_COLOR":     true,
+	}
+	var ret []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if !overrides[name] {
+			ret = append(ret, kv)
+		}
+	}
+	return append(ret, "AWS_PROFILE="+profile)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/buzzsurfr/wasp/internal/export"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export profiles for other tools",
+	Long: `Export writes the profiles in the AWS config file in the formats other
+tools keep their AWS settings in, so they don't have to be kept in step by
+hand:
+
+  granted    AWS config profiles that sign in with granted credential-process
+  aws-vault  AWS config profiles with the legacy SSO keys aws-vault reads
+  terraform  a provider "aws" block with an alias for each profile
+  steampipe  an aws.spc connection for each profile and an aggregator
+  json       the profiles and SSO sessions, with account names
+
+Profiles can be picked with the same filters as wasp list:
+
+  wasp export --format terraform --session corp > providers.tf
+  wasp export --format steampipe > ~/.steampipe/config/aws.spc`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatFlag, _ := cmd.Flags().GetString("format")
+		format, err := export.ParseFormat(formatFlag)
+		if err != nil {
+			return err
+		}
+		filter := profileFilter{}
+		filter.session, _ = cmd.Flags().GetString("session")
+		filter.account, _ = cmd.Flags().GetString("account")
+		filter.role, _ = cmd.Flags().GetString("role")
+		filter.ssoOnly, _ = cmd.Flags().GetBool("sso-only")
+		filter.managed, _ = cmd.Flags().GetBool("managed")
+		filter.unmanaged, _ = cmd.Flags().GetBool("unmanaged")
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		fillAccountNames(cf)
+		return export.Write(os.Stdout, format, cf, filter.apply(cf.Profiles.List()))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringP("format", "f", "json", "export format (granted, aws-vault, terraform, steampipe, json)")
+	exportCmd.Flags().String("session", "", "only include profiles for this SSO session")
+	exportCmd.Flags().String("account", "", "only include accounts whose name or ID matches this pattern")
+	exportCmd.Flags().String("role", "", "only include roles matching this pattern")
+	exportCmd.Flags().Bool("sso-only", false, "only include profiles that use an SSO session")
+	exportCmd.Flags().Bool("managed", false, "only include profiles wasp manages")
+	exportCmd.Flags().Bool("unmanaged", false, "only include profiles wasp doesn't manage")
+	exportCmd.MarkFlagsMutuallyExclusive("managed", "unmanaged")
+}
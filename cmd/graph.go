@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/spf13/cobra"
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph [profile|alias...]",
+	Short: "Show how profiles refer to each other",
+	Long: `Graph shows the references between the sections of the AWS config file:
+source_profile, sso_session, services and credential_source. Given
+profiles, only what they refer to, directly or not, is shown.
+
+The tree format lists each SSO session and credential source with the
+profiles that get credentials from it, and role profiles under the
+profile they assume a role from. The dot and mermaid formats draw every
+reference for Graphviz and Mermaid:
+
+  wasp graph --format dot | dot -Tsvg > config.svg
+
+References to sections that don't exist are marked ✗ (red in dot and
+mermaid) and source_profile cycles ↻.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		g := cf.Graph()
+		if len(args) > 0 {
+			wc, err := loadWaspConfig()
+			if err != nil {
+				return err
+			}
+			var from []awsconfig.Node
+			for _, arg := range args {
+				profile, err := resolveProfile(cf, wc, arg)
+				if err != nil {
+					return err
+				}
+				from = append(from, awsconfig.Node{Type: "profile", Name: profile.Name})
+			}
+			g = g.Reachable(from...)
+		}
+
+		switch format {
+		case "tree":
+			return g.WriteTree(os.Stdout)
+		case "dot":
+			return g.WriteDOT(os.Stdout)
+		case "mermaid":
+			return g.WriteMermaid(os.Stdout)
+		}
+		return fmt.Errorf("unknown graph format %q, expected one of: tree, dot, mermaid", format)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringP("format", "f", "tree", "graph format (tree, dot, mermaid)")
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/buzzsurfr/wasp/internal/state"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recently used profiles",
+	Long: `History shows the profiles you've switched to, most recent first. The
+switch command uses it to put recently used profiles at the top.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WHEN\tPROFILE\tFAVORITE")
+		for i, entry := range st.History {
+			if limit > 0 && i >= limit {
+				break
+			}
+			favorite := ""
+			if st.IsFavorite(entry.Profile) {
+				favorite = "★"
+			}
+			fmt.Fprintf(w, "%s ago\t%s\t%s\n", formatDuration(now.Sub(entry.Time)), entry.Profile, favorite)
+		}
+		return w.Flush()
+	},
+}
+
+var historyClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Forget recently used profiles",
+	Long:  `Clear forgets every profile switch. Favorites are kept.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.Load()
+		if err != nil {
+			return err
+		}
+		st.ClearHistory()
+		return st.Save()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyClearCmd)
+
+	historyCmd.Flags().IntP("limit", "n", 20, "number of entries to show (0 for all)")
+}
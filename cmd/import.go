@@ -0,0 +1,131 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/buzzsurfr/wasp/internal/importer"
+	"github.com/spf13/cobra"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import profiles from another AWS config file or tool",
+	Long: `Import adds the profiles and SSO sessions in another file to the AWS
+config file. The file can be:
+
+  aws        another AWS config file
+  aws-vault  an aws-vault config, usually with legacy SSO keys
+  granted    an AWS config file written by granted sso generate
+  json/yaml  the inventory wasp export --format json writes
+
+The format is guessed from the file extension unless --format is given.
+Profiles with legacy or granted SSO keys are imported with an sso-session,
+using the existing session with the same start URL if there is one.
+
+With files.managed set, sections are imported into the first files.sources
+entry rather than the file wasp keeps to itself.
+
+Sections that already exist with other keys are conflicts; --on-conflict
+skips them (the default), replaces them, imports them under a new name or
+fails without importing anything. --dry-run shows the sections import would
+add or change:
+
+  wasp import ~/.aws/config.vault --format aws-vault --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		format := importer.DetectFormat(path)
+		if cmd.Flags().Changed("format") {
+			formatFlag, _ := cmd.Flags().GetString("format")
+			var err error
+			if format, err = importer.ParseFormat(formatFlag); err != nil {
+				return err
+			}
+		}
+		strategy, _ := cmd.Flags().GetString("on-conflict")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		set, err := importer.Read(path, format)
+		if err != nil {
+			return err
+		}
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		// Imported profiles are hand-written as far as wasp is concerned, so
+		// they don't belong in the file wasp keeps to itself
+		opts := importer.Options{Strategy: strategy}
+		if wc.Files.Managed != "" {
+			sources := wc.Files.SourcePaths()
+			if len(sources) == 0 {
+				return errors.New("files.managed is wasp's own file; add a files.sources entry to the wasp config to import into")
+			}
+			opts.File = sources[0]
+		}
+
+		result, err := importer.Merge(cf, set, opts)
+		if err != nil {
+			return err
+		}
+		if len(result.Conflicts) > 0 {
+			fmt.Fprintln(os.Stderr, "Sections that conflict with the AWS config file:")
+			for _, c := range result.Conflicts {
+				fmt.Fprintln(os.Stderr, " ", c)
+			}
+		}
+
+		if dryRun {
+			sources := make(map[string]map[string]string)
+			for _, name := range slices.Concat(result.SSOSessions, result.Profiles) {
+				sources[name] = make(map[string]string)
+			}
+			for _, change := range cf.Changes() {
+				if m, ok := sources[change.Name]; ok {
+					for _, key := range change.Keys {
+						m[key.Key] = filepath.Base(path)
+					}
+				}
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			printChanges(w, cf.Changes(), sources)
+			return w.Flush()
+		}
+
+		if err := saveConfigFile(cf); err != nil {
+			return err
+		}
+		summary := []string{fmt.Sprintf("%d profiles", len(result.Profiles))}
+		if len(result.SSOSessions) > 0 {
+			summary = append(summary, fmt.Sprintf("%d SSO sessions", len(result.SSOSessions)))
+		}
+		fmt.Fprintf(os.Stderr, "Imported %s from %s", strings.Join(summary, " and "), path)
+		if result.Unchanged > 0 {
+			fmt.Fprintf(os.Stderr, "; %d sections were already there", result.Unchanged)
+		}
+		fmt.Fprintln(os.Stderr)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringP("format", "f", "", "import format (aws, aws-vault, granted, json, yaml); guessed from the file extension by default")
+	importCmd.Flags().String("on-conflict", importer.SkipConflicts, "what to do with sections that exist with other keys: skip, replace, rename or fail")
+	importCmd.Flags().Bool("dry-run", false, "show the sections import would add or change, without changing anything")
+}
@@ -5,19 +5,11 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
-	"github.com/aws/aws-sdk-go-v2/service/sso"
-	"github.com/aws/aws-sdk-go-v2/service/sso/types"
-	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+
 	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
 	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/table"
@@ -36,11 +28,14 @@ discovery of AWS SSO sessions.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		// Load AWS config file
-		cf, err := awsconfig.NewFromConfig(config.DefaultSharedConfigFilename())
+		cf, err := loadConfigFile()
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "No AWS config file found. Create one first with: aws configure")
 			os.Exit(1)
 		}
+		wc, err := loadWaspConfig()
+		cobra.CheckErr(err)
+		cobra.CheckErr(wc.Validate())
 
 		// Create Bubbles table for SSO sessions
 		t := cf.SSOSessions.TableModel(10)
@@ -49,67 +44,23 @@ discovery of AWS SSO sessions.`,
 		t.SetStyles(tableStyle)
 
 		// Choose a sso session
-		var ssoSession, ssoRegion string
-		ssoSession = "corp"
-		ssoRegion = "us-east-1"
-
-		cfg, err := config.LoadDefaultConfig(context.Background())
-		if err != nil {
-			panic(err)
+		session := awsconfig.NewSSOSession("corp")
+		session.Region = "us-east-1"
+		if len(wc.Sessions) > 0 {
+			session.Name = wc.Sessions[0].Name
 		}
-		cfg.Region = ssoRegion
-
-		ssoClient := sso.NewFromConfig(cfg)
-		ssoOidcClient := ssooidc.NewFromConfig(cfg)
-		tokenPath, err := ssocreds.StandardCachedTokenFilepath(ssoSession)
-		if err != nil {
-			panic(err)
+		if s, err := cf.GetSSOSession(session.Name); err == nil {
+			session = s
 		}
 
-		// Read JSON from tokenPath and parse sso_start_url
-		tokenFile, err := os.ReadFile(tokenPath)
-		if err != nil {
-			panic(err)
-		}
-
-		var ssoData SSOData
-		err = json.Unmarshal(tokenFile, &ssoData)
-		if err != nil {
-			panic(err)
-		}
-
-		ssoTokenProvider := ssocreds.NewSSOTokenProvider(ssoOidcClient, tokenPath)
-
-		var provider aws.CredentialsProvider
-		provider = ssocreds.New(ssoClient, "123456789012", "no-role", ssoData.StartURL, func(options *ssocreds.Options) {
-			options.SSOTokenProvider = ssoTokenProvider
-		})
-
-		// Wrap the provider with aws.CredentialsCache to cache the credentials until their expire time
-		provider = aws.NewCredentialsCache(provider)
+		ctx := context.Background()
+		clients, err := newSSOClients(ctx, session)
+		cobra.CheckErr(err)
 
 		// List associated AWS accounts and roles
-		var listAccounts *sso.ListAccountsOutput
-		for retries := 0; retries < 2; retries++ {
-			listAccounts, err = ssoClient.ListAccounts(context.TODO(), &sso.ListAccountsInput{
-				AccessToken: &ssoData.AccessToken,
-			})
-			if err != nil {
-				var aerr *types.UnauthorizedException
-				if errors.As(err, &aerr) {
-					fmt.Printf("Unauthorized. Attempting to login to %s SSO session.\n", ssoSession)
-					cmd := exec.Command("aws", "sso", "login", "--sso-session", ssoSession)
-					if err := cmd.Run(); err != nil {
-						fmt.Printf("Unauthorized. Please run `aws sso login --sso-session %s` to refresh your session.\n", ssoSession)
-						os.Exit(1)
-					} else {
-						continue
-					}
-				} else {
-					panic(err)
-				}
-			}
-		}
+		accounts, err := clients.accounts(ctx)
+		cobra.CheckErr(err)
+		cobra.CheckErr(cacheAccounts(session.Name, accounts))
 
 		var accountRows []table.Row
 		accountColWidths := make(map[string]int)
@@ -118,25 +69,24 @@ discovery of AWS SSO sessions.`,
 		accountColWidths["ID"] = 0
 		accountColWidths["Role"] = 0
 
-		for _, account := range listAccounts.AccountList {
-			// Create account table rows
-			accountColWidths["Name"] = max(accountColWidths["Name"], len(aws.ToString(account.AccountName)))
-			accountColWidths["Email Address"] = max(accountColWidths["Email Address"], len(aws.ToString(account.EmailAddress)))
-			accountColWidths["ID"] = max(accountColWidths["ID"], len(aws.ToString(account.AccountId)))
-
-			// Account Roles
-			listAccountRoles, err := ssoClient.ListAccountRoles(context.TODO(), &sso.ListAccountRolesInput{
-				AccessToken: &ssoData.AccessToken,
-				AccountId:   account.AccountId,
-			})
-			if err != nil {
-				panic(err)
-			}
-			for _, role := range listAccountRoles.RoleList {
-				accountRows = append(accountRows, table.Row{aws.ToString(account.AccountName), aws.ToString(account.EmailAddress), aws.ToString(account.AccountId), aws.ToString(role.RoleName)})
-				accountColWidths["Role"] = max(accountColWidths["Role"], len(aws.ToString(role.RoleName)))
+		filters := wc.Session(session.Name)
+		for _, account := range accounts {
+			// Account Roles the filters allow
+			for _, role := range account.Roles {
+				if !filters.Allows(nameData(session, account, role)) {
+					continue
+				}
+				// Create account table rows
+				accountRows = append(accountRows, table.Row{account.Name, account.Email, account.ID, role})
+				accountColWidths["Name"] = max(accountColWidths["Name"], len(account.Name))
+				accountColWidths["Email Address"] = max(accountColWidths["Email Address"], len(account.Email))
+				accountColWidths["ID"] = max(accountColWidths["ID"], len(account.ID))
+				accountColWidths["Role"] = max(accountColWidths["Role"], len(role))
 			}
 		}
+		if len(accountRows) == 0 {
+			cobra.CheckErr(fmt.Errorf("no account roles in SSO session %s pass the filters in %s", session.Name, wc.Path()))
+		}
 		// Create bubbles table ssoSessionColumns based on colWidths
 		accountColumns := []table.Column{
 			{Title: "Name", Width: accountColWidths["Name"]},
@@ -150,14 +100,19 @@ discovery of AWS SSO sessions.`,
 			table.WithColumns(accountColumns),
 			table.WithRows(accountRows),
 			table.WithFocused(true),
-			table.WithHeight(min(len(accountRows), 10)),
+			table.WithHeight(min(len(accountRows), wc.UI.Rows(10))),
 		)
 
 		at.SetStyles(tableStyle)
 
 		// Choose an account
-		ap := tea.NewProgram(newAccountsModel(at, accountColumns))
-		am, err := ap.Run()
+		var am tea.Model
+		if useTree(cmd, wc) {
+			am, err = chooseAccountFromTree(accountRows, wc.UI.Rows(15))
+		} else {
+			ap := tea.NewProgram(newAccountsModel(at, accountColumns))
+			am, err = ap.Run()
+		}
 		if err != nil {
 			fmt.Println("Error running program:", err)
 			os.Exit(1)
@@ -166,21 +121,37 @@ discovery of AWS SSO sessions.`,
 		// Assert the final tea.Model to our local model and print the choice.
 		var profile *awsconfig.Profile
 		if am, ok := am.(accountsModel); ok && am.accountName != "" {
-			profile_name := fmt.Sprintf("%s_%s", am.accountName, am.roleName)
-			profile = cf.Profile(profile_name)
-			profile.Name = profile_name
-			profile.SSOSession = ssoSession
-			profile.AccountID = am.accountId
-			profile.RoleName = am.roleName
+			planned, collisions, err := wc.ResolveNames([]waspconfig.NameData{{
+				Session:      session.Name,
+				SSORegion:    session.Region,
+				AccountID:    am.accountId,
+				AccountName:  am.accountName,
+				AccountEmail: am.emailAddress,
+				RoleName:     am.roleName,
+			}}, profileTargets(cf))
+			cobra.CheckErr(err)
+			printCollisions(collisions)
+			if existing, err := cf.GetProfile(planned[0].Name); err == nil && !existing.Managed {
+				fmt.Fprintf(os.Stderr, "Profile %s already exists and isn't managed by wasp; leaving it alone.\n", existing.Name)
+				os.Exit(1)
+			}
+			profile = generateProfile(cf, wc, planned[0].Name, planned[0].Data)
 		} else {
 			os.Exit(1)
 		}
 		fmt.Printf("[profile %s]\nsso_session = %s\nsso_account_id = %s\nsso_role_name = %s\n", profile.Name, profile.SSOSession, profile.AccountID, profile.RoleName)
 
-		err = cf.Update()
+		err = saveConfigFile(cf)
 		if err != nil {
 			panic(err)
 		}
+
+		// Start the wasp config with the session that was just set up
+		if !wc.Exists() {
+			wc.Sessions = []waspconfig.Session{{Name: session.Name}}
+			cobra.CheckErr(wc.Save())
+			fmt.Fprintln(os.Stderr, "Created", wc.Path())
+		}
 	},
 }
 
@@ -190,6 +161,8 @@ var tableStyle table.Styles  // tableStyle is the default style for any table
 func init() {
 	rootCmd.AddCommand(initCmd)
 
+	initCmd.Flags().Bool("tree", false, "group roles by account")
+
 	baseStyle = lipgloss.NewStyle().
 		BorderStyle(lipgloss.HiddenBorder()).
 		BorderForeground(lipgloss.Color("240"))
@@ -232,17 +205,6 @@ func (km keyMap) FullHelp() [][]key.Binding {
 	}
 }
 
-type SSOData struct {
-	AccessToken      string `json:"accessToken"`
-	ExpiresAt        string `json:"expiresAt"`
-	Region           string `json:"region"`
-	StartURL         string `json:"startUrl"`
-	SSORegion        string `json:"ssoRegion"`
-	AccountID        string `json:"accountId"`
-	RoleName         string `json:"roleName"`
-	IdentityProvider string `json:"identityProvider"`
-}
-
 type accountsModel struct {
 	accountName  string
 	accountId    string
@@ -261,48 +223,52 @@ func newAccountsModel(choices table.Model, columns []table.Column) accountsModel
 		names:   showFirstColumnOnly(columns),
 		choices: choices,
 		help:    help.New(),
-		keyMap: keyMap{
-			LineUp: key.NewBinding(
-				key.WithKeys("up", "k"),
-				key.WithHelp("↑/k", "up"),
-			),
-			LineDown: key.NewBinding(
-				key.WithKeys("down", "j"),
-				key.WithHelp("↓/j", "down"),
-			),
-			PageUp: key.NewBinding(
-				key.WithKeys("b", "pgup"),
-				key.WithHelp("b/pgup", "page up"),
-			),
-			PageDown: key.NewBinding(
-				key.WithKeys("f", "pgdown", " "),
-				key.WithHelp("f/pgdn", "page down"),
-			),
-			HalfPageUp: key.NewBinding(
-				key.WithKeys("u", "ctrl+u"),
-				key.WithHelp("u", "½ page up"),
-			),
-			HalfPageDown: key.NewBinding(
-				key.WithKeys("d", "ctrl+d"),
-				key.WithHelp("d", "½ page down"),
-			),
-			GotoTop: key.NewBinding(
-				key.WithKeys("home", "g"),
-				key.WithHelp("g/home", "go to start"),
-			),
-			GotoBottom: key.NewBinding(
-				key.WithKeys("end", "G"),
-				key.WithHelp("G/end", "go to end"),
-			),
-			Expand: key.NewBinding(
-				key.WithKeys("right", "l"),
-				key.WithHelp("→/l", "expand"),
-			),
-			Collapse: key.NewBinding(
-				key.WithKeys("left", "h"),
-				key.WithHelp("←/h", "collapse"),
-			),
-		},
+		keyMap:  newKeyMap(),
+	}
+}
+
+func newKeyMap() keyMap {
+	return keyMap{
+		LineUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "up"),
+		),
+		LineDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("b", "pgup"),
+			key.WithHelp("b/pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("f", "pgdown", " "),
+			key.WithHelp("f/pgdn", "page down"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("u", "ctrl+u"),
+			key.WithHelp("u", "½ page up"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("d", "ctrl+d"),
+			key.WithHelp("d", "½ page down"),
+		),
+		GotoTop: key.NewBinding(
+			key.WithKeys("home", "g"),
+			key.WithHelp("g/home", "go to start"),
+		),
+		GotoBottom: key.NewBinding(
+			key.WithKeys("end", "G"),
+			key.WithHelp("G/end", "go to end"),
+		),
+		Expand: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("→/l", "expand"),
+		),
+		Collapse: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("←/h", "collapse"),
+		),
 	}
 }
 
@@ -338,6 +304,32 @@ func (m accountsModel) View() tea.View {
 	return tea.NewView("\nAWS accounts in session:\n" + baseStyle.Render(m.choices.View()) + "\n" + m.help.View(m.keyMap))
 }
 
+// chooseAccountFromTree lets the user pick a role from accounts grouped in
+// a tree, returning the choice as an accountsModel like the table view does.
+func chooseAccountFromTree(rows []table.Row, height int) (tea.Model, error) {
+	var leaves []treeLeaf
+	for _, row := range rows {
+		leaves = append(leaves, treeLeaf{
+			path:  []string{accountLabel(row[0], row[2])},
+			label: row[3],
+			row:   row,
+		})
+	}
+	tp := tea.NewProgram(newTreeModel("AWS accounts in session:", buildTree(leaves), height))
+	tm, err := tp.Run()
+	if err != nil {
+		return nil, err
+	}
+	var am accountsModel
+	if tm, ok := tm.(treeModel); ok && tm.selected != nil {
+		am.accountName = tm.selected[0]
+		am.emailAddress = tm.selected[1]
+		am.accountId = tm.selected[2]
+		am.roleName = tm.selected[3]
+	}
+	return am, nil
+}
+
 func showFirstColumnOnly(columns []table.Column) []table.Column {
 	ret := []table.Column{}
 	for i, col := range columns {
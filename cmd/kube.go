@@ -0,0 +1,165 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/kube"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+	"github.com/spf13/cobra"
+)
+
+// kubeCmd represents the kube command
+var kubeCmd = &cobra.Command{
+	Use:   "kube",
+	Short: "Manage kubeconfig contexts for EKS clusters",
+}
+
+var kubeSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Write kubeconfig contexts for EKS clusters",
+	Long: `Sync writes a kubeconfig context for every SSO profile and every EKS cluster
+in the profile's account, listed in a cluster inventory file
+(~/.wasp/clusters.yaml unless kube.clusters or --clusters says otherwise):
+
+  clusters:
+    - arn: arn:aws:eks:us-east-1:111111111111:cluster/main
+      endpoint: https://ABCD.gr7.us-east-1.eks.amazonaws.com
+      certificate_authority_data: LS0t...
+    - name: staging
+      account_id: "222222222222"
+      region: eu-west-1
+      endpoint: https://EFGH.yl4.eu-west-1.eks.amazonaws.com
+
+Users get their tokens from aws eks get-token --profile <profile>, and
+contexts are named by the kube.context template, "{{.Profile}}@{{.Cluster}}"
+by default. Only profiles for the sessions, accounts and roles wasp sync
+covers get contexts.
+
+Contexts wasp wrote earlier are updated, and removed once their profile
+or cluster is gone; contexts and users wasp didn't write are left alone.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+
+		clustersPath, _ := cmd.Flags().GetString("clusters")
+		if clustersPath == "" {
+			def, err := waspdir.Path("clusters.yaml")
+			if err != nil {
+				return err
+			}
+			clustersPath = wc.Kube.ClustersPath(def)
+		}
+		clusters, err := kube.LoadInventory(clustersPath)
+		if err != nil {
+			return err
+		}
+
+		kubeconfigPath, _ := cmd.Flags().GetString("kubeconfig")
+		if kubeconfigPath == "" {
+			def, err := kube.DefaultPath()
+			if err != nil {
+				return err
+			}
+			kubeconfigPath = wc.Kube.KubeconfigPath(def)
+		}
+		kc, err := kube.Load(kubeconfigPath)
+		if err != nil {
+			return err
+		}
+
+		// The profiles sync would generate, by the same filters
+		sessions, err := syncSessions(cf, wc)
+		if err != nil {
+			return err
+		}
+		var profiles []*awsconfig.Profile
+		for _, p := range cf.Profiles.List() {
+			if p.AliasFor != "" || p.AccountID == "" || p.RoleName == "" {
+				continue
+			}
+			if !slices.ContainsFunc(sessions, func(s *awsconfig.SSOSession) bool { return s.Name == p.SSOSession }) {
+				continue
+			}
+			if wc.Session(p.SSOSession).Allows(nameDataFor(p)) {
+				profiles = append(profiles, p)
+			}
+		}
+
+		entries, err := kube.Entries(clusters, profiles, wc)
+		if err != nil {
+			return err
+		}
+		changes, skipped := kc.Sync(entries)
+		if len(skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Left %d contexts alone that wasp didn't write:\n", len(skipped))
+			for _, name := range skipped {
+				fmt.Fprintln(os.Stderr, " ", name)
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if dryRun {
+			printKubeChanges(w, changes)
+			return w.Flush()
+		}
+		if len(changes) > 0 {
+			if err := kc.Save(); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d contexts to %s\n", len(entries)-len(skipped), kc.Path())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(kubeCmd)
+	kubeCmd.AddCommand(kubeSyncCmd)
+
+	kubeSyncCmd.Flags().String("clusters", "", "cluster inventory file (default kube.clusters or ~/.wasp/clusters.yaml)")
+	kubeSyncCmd.Flags().String("kubeconfig", "", "kubeconfig file to write (default kube.kubeconfig or the one kubectl uses)")
+	kubeSyncCmd.Flags().Bool("dry-run", false, "show what would change without writing the kubeconfig")
+}
+
+// nameDataFor returns what filters and templates see for a profile
+func nameDataFor(p *awsconfig.Profile) waspconfig.NameData {
+	return waspconfig.NameData{Session: p.SSOSession, AccountID: p.AccountID, AccountName: p.AccountName, AccountEmail: p.AccountEmail, RoleName: p.RoleName}
+}
+
+// printKubeChanges lists changed contexts and clusters like sync --dry-run
+func printKubeChanges(w *tabwriter.Writer, changes []kube.Change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, c := range changes {
+		mark := "~"
+		switch {
+		case c.New:
+			mark = "+"
+		case c.Removed:
+			mark = "-"
+		}
+		if c.Kind == "context" && !c.Removed {
+			fmt.Fprintf(w, "%s [context %s]\tprofile %s\t%s\n", mark, c.Name, c.Entry.Profile, c.Entry.Cluster.ARN)
+		} else {
+			fmt.Fprintf(w, "%s [%s %s]\t\t\n", mark, c.Kind, c.Name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/buzzsurfr/wasp/internal/lint"
+	"github.com/buzzsurfr/wasp/internal/output"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:     "lint",
+	Aliases: []string{"doctor"},
+	Short:   "Check the AWS config file for problems",
+	Long: `Lint checks the AWS config file for problems the AWS CLI and SDKs would trip
+over: profiles using SSO sessions or source profiles that don't exist,
+incomplete SSO sessions, sections and keys defined twice, source_profile
+cycles, regions that don't exist, unknown keys, profiles mixing legacy and
+sso-session SSO settings, and expired SSO tokens.
+
+--fix applies the fixes that can't change what a profile does, such as
+merging duplicate sections that agree and removing legacy SSO keys that
+repeat the session's. Lint exits non-zero if it finds anything at least as
+severe as --fail-on, so it can run in CI:
+
+  wasp lint --output json --fail-on warning`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFlag, _ := cmd.Flags().GetString("output")
+		format, err := output.ParseFormat(outputFlag)
+		if err != nil {
+			return err
+		}
+		failOnFlag, _ := cmd.Flags().GetString("fail-on")
+		failOn, err := lint.ParseSeverity(failOnFlag)
+		if err != nil && failOnFlag != "never" {
+			return err
+		}
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		opts := lint.Options{Now: time.Now(), Token: loadCachedToken}
+		findings := lint.Run(cf, opts)
+
+		if fix {
+			if fixed := lint.Fix(cf, findings); fixed > 0 {
+				if err := saveConfigFile(cf); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Fixed %d problems.\n", fixed)
+				if cf, err = loadConfigFile(); err != nil {
+					return err
+				}
+				findings = lint.Run(cf, opts)
+			}
+		}
+
+		if err := output.Write(os.Stdout, format, findingsData(findings)); err != nil {
+			return err
+		}
+		if failOnFlag != "never" {
+			if n := lint.Count(findings, failOn); n > 0 {
+				return fmt.Errorf("found %d problems at %s or above", n, failOn)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().Bool("fix", false, "apply safe fixes")
+	lintCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml, csv)")
+	lintCmd.Flags().String("fail-on", "error", "exit non-zero on findings of this severity or above: info, warning, error or never")
+}
+
+// loadCachedToken loads the cached SSO token for a session
+func loadCachedToken(session string) (*ssocache.Token, error) {
+	path, err := ssocache.Path(session)
+	if err != nil {
+		return nil, err
+	}
+	return ssocache.Load(path)
+}
+
+func findingsData(findings []lint.Finding) output.Data {
+	if findings == nil {
+		findings = []lint.Finding{}
+	}
+	data := output.Data{
+		Headers: []string{"Severity", "Check", "Location", "Section", "Message", "Fixable"},
+		Records: findings,
+	}
+	for _, f := range findings {
+		fixable := "no"
+		if f.Fixable {
+			fixable = "yes"
+		}
+		data.Rows = append(data.Rows, []string{f.Severity.String(), f.Check, f.Location(), f.Section, f.Message, fixable})
+	}
+	return data
+}
@@ -0,0 +1,288 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:       "list {profiles|sessions|accounts}",
+	Aliases:   []string{"ls"},
+	Short:     "List profiles, SSO sessions or accounts",
+	ValidArgs: []string{"profiles", "sessions", "accounts"},
+	Long: `List prints wasp's view of the AWS config file in a format scripts can
+consume. Profiles, sessions and accounts can be filtered by SSO session, and
+profiles and accounts by account (name or ID) and role; account and role
+filters accept glob patterns. --managed and --unmanaged pick out the
+profiles wasp sync maintains, or the others.
+
+  wasp list profiles --session corp --role 'Admin*' --output json
+  wasp list accounts --output csv --sort name`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFlag, _ := cmd.Flags().GetString("output")
+		format, err := output.ParseFormat(outputFlag)
+		if err != nil {
+			return err
+		}
+		filter := profileFilter{}
+		filter.session, _ = cmd.Flags().GetString("session")
+		filter.account, _ = cmd.Flags().GetString("account")
+		filter.role, _ = cmd.Flags().GetString("role")
+		filter.ssoOnly, _ = cmd.Flags().GetBool("sso-only")
+		filter.managed, _ = cmd.Flags().GetBool("managed")
+		filter.unmanaged, _ = cmd.Flags().GetBool("unmanaged")
+		sortKeys, _ := cmd.Flags().GetStringSlice("sort")
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		fillAccountNames(cf)
+		profiles := filter.apply(cf.Profiles.List())
+
+		var data output.Data
+		switch args[0] {
+		case "profiles":
+			data, err = profilesData(profiles, sortKeys)
+		case "sessions":
+			data, err = sessionsData(cf, profiles, filter.session, sortKeys)
+		case "accounts":
+			data, err = accountsData(profiles, sortKeys)
+		}
+		if err != nil {
+			return err
+		}
+		return output.Write(os.Stdout, format, data)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml, csv)")
+	listCmd.Flags().String("session", "", "only include this SSO session and its profiles")
+	listCmd.Flags().String("account", "", "only include accounts whose name or ID matches this pattern")
+	listCmd.Flags().String("role", "", "only include roles matching this pattern")
+	listCmd.Flags().Bool("sso-only", false, "only include profiles that use an SSO session")
+	listCmd.Flags().Bool("managed", false, "only include profiles wasp manages")
+	listCmd.Flags().Bool("unmanaged", false, "only include profiles wasp doesn't manage")
+	listCmd.MarkFlagsMutuallyExclusive("managed", "unmanaged")
+	listCmd.Flags().StringSlice("sort", []string{"name"}, "sort keys (name, session, account, account-id, role, profiles)")
+}
+
+// profileFilter selects profiles by the list command's filter flags
+type profileFilter struct {
+	session   string
+	account   string
+	role      string
+	ssoOnly   bool
+	managed   bool
+	unmanaged bool
+}
+
+func (f profileFilter) apply(profiles []*awsconfig.Profile) []*awsconfig.Profile {
+	var ret []*awsconfig.Profile
+	for _, p := range profiles {
+		if f.ssoOnly && p.SSOSession == "" {
+			continue
+		}
+		if f.managed && !p.Managed || f.unmanaged && p.Managed {
+			continue
+		}
+		if f.session != "" && p.SSOSession != f.session {
+			continue
+		}
+		if f.account != "" && !globMatch(f.account, p.AccountID) && !globMatch(f.account, p.AccountName) {
+			continue
+		}
+		if f.role != "" && !globMatch(f.role, p.RoleName) {
+			continue
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// globMatch reports whether value matches a shell glob pattern. Invalid
+// patterns only match themselves.
+func globMatch(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+	if err != nil {
+		return pattern == value
+	}
+	return ok
+}
+
+type profileRecord struct {
+	Name        string `json:"name" yaml:"name"`
+	SSOSession  string `json:"sso_session,omitempty" yaml:"sso_session,omitempty"`
+	AccountID   string `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	AccountName string `json:"account_name,omitempty" yaml:"account_name,omitempty"`
+	RoleName    string `json:"role_name,omitempty" yaml:"role_name,omitempty"`
+	Managed     bool   `json:"managed" yaml:"managed"`
+	File        string `json:"file" yaml:"file"`
+}
+
+type sessionRecord struct {
+	Name     string `json:"name" yaml:"name"`
+	StartURL string `json:"start_url" yaml:"start_url"`
+	Region   string `json:"region" yaml:"region"`
+	Profiles int    `json:"profiles" yaml:"profiles"`
+}
+
+type accountRecord struct {
+	AccountID   string   `json:"account_id" yaml:"account_id"`
+	AccountName string   `json:"account_name,omitempty" yaml:"account_name,omitempty"`
+	SSOSession  string   `json:"sso_session" yaml:"sso_session"`
+	Roles       []string `json:"roles" yaml:"roles"`
+}
+
+func profilesData(profiles []*awsconfig.Profile, sortKeys []string) (output.Data, error) {
+	records := make([]profileRecord, 0, len(profiles))
+	for _, p := range profiles {
+		records = append(records, profileRecord{p.Name, p.SSOSession, p.AccountID, p.AccountName, p.RoleName, p.Managed, p.Source})
+	}
+	less, err := sortBy(sortKeys, map[string]func(i, j int) int{
+		"name":       func(i, j int) int { return strings.Compare(records[i].Name, records[j].Name) },
+		"session":    func(i, j int) int { return strings.Compare(records[i].SSOSession, records[j].SSOSession) },
+		"account":    func(i, j int) int { return strings.Compare(records[i].AccountName, records[j].AccountName) },
+		"account-id": func(i, j int) int { return strings.Compare(records[i].AccountID, records[j].AccountID) },
+		"role":       func(i, j int) int { return strings.Compare(records[i].RoleName, records[j].RoleName) },
+	})
+	if err != nil {
+		return output.Data{}, err
+	}
+	sort.SliceStable(records, less)
+
+	data := output.Data{
+		Headers: []string{"Profile", "Account Name", "SSO Session", "Account ID", "Role Name", "Managed"},
+		Records: records,
+	}
+	for _, r := range records {
+		managed := "no"
+		if r.Managed {
+			managed = "yes"
+		}
+		data.Rows = append(data.Rows, []string{r.Name, r.AccountName, r.SSOSession, r.AccountID, r.RoleName, managed})
+	}
+	return data, nil
+}
+
+// sessionsData lists the SSO sessions, or only the one named by session,
+// with how many of profiles use each
+func sessionsData(cf *awsconfig.ConfigFile, profiles []*awsconfig.Profile, session string, sortKeys []string) (output.Data, error) {
+	counts := make(map[string]int)
+	for _, p := range profiles {
+		counts[p.SSOSession]++
+	}
+	records := make([]sessionRecord, 0)
+	for _, s := range cf.SSOSessions.List() {
+		if session != "" && s.Name != session {
+			continue
+		}
+		records = append(records, sessionRecord{s.Name, s.StartURL, s.Region, counts[s.Name]})
+	}
+	less, err := sortBy(sortKeys, map[string]func(i, j int) int{
+		"name":     func(i, j int) int { return strings.Compare(records[i].Name, records[j].Name) },
+		"session":  func(i, j int) int { return strings.Compare(records[i].Name, records[j].Name) },
+		"profiles": func(i, j int) int { return records[j].Profiles - records[i].Profiles },
+	})
+	if err != nil {
+		return output.Data{}, err
+	}
+	sort.SliceStable(records, less)
+
+	data := output.Data{
+		Headers: []string{"Name", "Start URL", "Region", "Profiles"},
+		Records: records,
+	}
+	for _, r := range records {
+		data.Rows = append(data.Rows, []string{r.Name, r.StartURL, r.Region, strconv.Itoa(r.Profiles)})
+	}
+	return data, nil
+}
+
+func accountsData(profiles []*awsconfig.Profile, sortKeys []string) (output.Data, error) {
+	type key struct{ session, id string }
+	index := make(map[key]int)
+	records := make([]accountRecord, 0)
+	for _, p := range profiles {
+		if p.AccountID == "" {
+			continue
+		}
+		k := key{p.SSOSession, p.AccountID}
+		i, ok := index[k]
+		if !ok {
+			i = len(records)
+			index[k] = i
+			records = append(records, accountRecord{AccountID: p.AccountID, SSOSession: p.SSOSession})
+		}
+		if p.AccountName != "" {
+			records[i].AccountName = p.AccountName
+		}
+		if p.RoleName != "" {
+			records[i].Roles = append(records[i].Roles, p.RoleName)
+		}
+	}
+	for i := range records {
+		sort.Strings(records[i].Roles)
+	}
+	less, err := sortBy(sortKeys, map[string]func(i, j int) int{
+		"name":       func(i, j int) int { return strings.Compare(records[i].AccountName, records[j].AccountName) },
+		"account":    func(i, j int) int { return strings.Compare(records[i].AccountName, records[j].AccountName) },
+		"account-id": func(i, j int) int { return strings.Compare(records[i].AccountID, records[j].AccountID) },
+		"session":    func(i, j int) int { return strings.Compare(records[i].SSOSession, records[j].SSOSession) },
+	})
+	if err != nil {
+		return output.Data{}, err
+	}
+	sort.SliceStable(records, less)
+
+	data := output.Data{
+		Headers: []string{"Account ID", "Account Name", "SSO Session", "Roles"},
+		Records: records,
+	}
+	for _, r := range records {
+		data.Rows = append(data.Rows, []string{r.AccountID, r.AccountName, r.SSOSession, strings.Join(r.Roles, ",")})
+	}
+	return data, nil
+}
+
+// sortBy builds a less function that compares by each key in turn, falling
+// through to the next key on ties.
+func sortBy(keys []string, comparators map[string]func(i, j int) int) (func(i, j int) bool, error) {
+	var cmps []func(i, j int) int
+	for _, k := range keys {
+		cmp, ok := comparators[k]
+		if !ok {
+			var valid []string
+			for name := range comparators {
+				valid = append(valid, name)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("unknown sort key %q, expected one of: %s", k, strings.Join(valid, ", "))
+		}
+		cmps = append(cmps, cmp)
+	}
+	return func(i, j int) bool {
+		for _, cmp := range cmps {
+			if c := cmp(i, j); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}, nil
+}
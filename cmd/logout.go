@@ -0,0 +1,129 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sso/types"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+	"github.com/spf13/cobra"
+)
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout [session]",
+	Short: "Sign out of AWS SSO sessions",
+	Long: `Logout signs out of an SSO session: the cached access token is revoked with
+AWS SSO and deleted, along with anything wasp has cached for the session.
+
+If the current AWS_PROFILE belongs to a session that was signed out, an unset
+command is printed so the shell stops using it:
+
+  eval $(wasp logout corp)`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		var sessions []*awsconfig.SSOSession
+		if all {
+			sessions = cf.SSOSessions.List()
+		} else {
+			session, err := resolveSSOSession(cf, args)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, session)
+		}
+
+		// Keep going past a session that fails, so one bad session doesn't
+		// leave the others' tokens behind
+		ctx := context.Background()
+		loggedOut := make(map[string]bool)
+		var errs []error
+		for _, session := range sessions {
+			if err := logout(ctx, session); err != nil {
+				errs = append(errs, fmt.Errorf("SSO session %s: %w", session.Name, err))
+				continue
+			}
+			loggedOut[session.Name] = true
+			fmt.Fprintf(os.Stderr, "Logged out of %s SSO session.\n", session.Name)
+		}
+
+		// Clear the exported profile if it belonged to one of the sessions
+		for _, env := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+			profile, err := cf.GetProfile(os.Getenv(env))
+			if err == nil && loggedOut[profile.SSOSession] {
+				fmt.Printf("unset %s\n", env)
+				if env == "AWS_PROFILE" {
+					fmt.Println("unset WASP_PROFILE_TAGS WASP_PROMPT_COLOR")
+				}
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+
+	logoutCmd.Flags().Bool("all", false, "log out of every SSO session")
+}
+
+// logout revokes the session's cached token and deletes it, along with the
+// wasp cache for the session. A token the service already considers invalid,
+// or can't be reached to revoke, is still deleted locally.
+func logout(ctx context.Context, session *awsconfig.SSOSession) error {
+	clients, err := newSSOClients(ctx, session)
+	if err != nil {
+		return err
+	}
+	dir, err := ssocache.Dir()
+	if err != nil {
+		return err
+	}
+
+	paths := []string{clients.tokenPath}
+	if session.StartURL != "" {
+		if path, err := ssocache.Path(session.StartURL); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		token, err := ssocache.Load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if !token.Expired(time.Now()) {
+			_, err := clients.sso.Logout(ctx, &sso.LogoutInput{
+				AccessToken: aws.String(token.AccessToken),
+			})
+			var aerr *types.UnauthorizedException
+			if err != nil && !errors.As(err, &aerr) {
+				fmt.Fprintf(os.Stderr, "Warning: couldn't revoke the token of SSO session %s, deleting it anyway: %v\n", session.Name, err)
+			}
+		}
+		if err := ssocache.Remove(dir, path); err != nil {
+			return err
+		}
+	}
+
+	return waspdir.RemoveSessionCache(session.Name)
+}
@@ -0,0 +1,426 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/manifest"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/spf13/cobra"
+)
+
+// manifestCmd represents the manifest command
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Work with team manifests",
+	Long: `A team manifest lists the SSO sessions, accounts, roles, naming rules,
+chained roles and aliases a team's AWS config should have, so it can be kept
+in git and applied by everyone:
+
+  version: 1
+  name: platform
+  sessions:
+    - name: corp
+      start_url: https://corp.awsapps.com/start
+      region: us-east-1
+      accounts:
+        - id: "111111111111"
+          name: Acme Production
+          roles: [AdministratorAccess, ReadOnly]
+  naming:
+    template: "{{.AccountName}}_{{.RoleName}}"
+  defaults:
+    region: us-east-1
+  rules: []
+  chains: []
+  profiles:
+    - name: ci
+      role_arn: arn:aws:iam::111111111111:role/CI
+      credential_source: Ec2InstanceMetadata
+  aliases:
+    prod: Acme Production_AdministratorAccess
+
+Naming, defaults, rules and chains work as in the wasp config. wasp plan
+shows what applying a manifest would change, wasp apply changes it, and
+wasp manifest generate writes a manifest for the current AWS config.`,
+}
+
+var manifestGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write a manifest for the current AWS config",
+	Long: `Generate writes a manifest with every SSO session in the AWS config file,
+the account roles its profiles sign in to, and the naming, defaults, rules,
+chains and aliases of the wasp config. Profiles the naming template and
+chains don't produce, and other role profiles, are listed by name.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		wc, err := loadWaspConfig()
+		if err != nil {
+			return err
+		}
+		fillAccountNames(cf)
+		m, err := manifest.Generate(name, cf, wc)
+		if err != nil {
+			return err
+		}
+		return m.Write(os.Stdout)
+	},
+}
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan -f MANIFEST",
+	Short: "Show what applying a team manifest would change",
+	Long: `Plan shows the sections applying a team manifest would add (+), change
+(~) or remove (-), the keys it would remove from the profiles it manages
+(-), and the aliases it would set, without changing anything.
+See wasp manifest --help for the manifest format.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plan, _, _, err := planFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		return plan.print(os.Stdout)
+	},
+}
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply -f MANIFEST",
+	Short: "Make the AWS config match a team manifest",
+	Long: `Apply reconciles the AWS config file with a team manifest: it creates and
+updates the SSO sessions and profiles the manifest lists, removes profiles
+an earlier apply of the same manifest created that it no longer lists, and
+sets its aliases in the wasp config. Keys and aliases an earlier apply
+wrote that the manifest no longer sets, such as dropped defaults, are
+removed. It shows the plan and asks before changing anything unless
+--auto-approve is given.
+
+Like sync, apply only changes profiles wasp manages; --adopt takes over
+hand-written profiles for the same account roles. Profiles created by
+another manifest are left alone.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+		plan, cf, wc, err := planFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if err := plan.print(os.Stdout); err != nil {
+			return err
+		}
+		if plan.empty() {
+			if plan.saveAliases {
+				return wc.Save()
+			}
+			return nil
+		}
+		if !autoApprove && !confirm("Apply these changes?") {
+			fmt.Fprintln(os.Stderr, "Apply cancelled.")
+			return nil
+		}
+		if err := saveConfigFile(cf); err != nil {
+			return err
+		}
+		if plan.saveAliases {
+			if err := wc.Save(); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(os.Stderr, "Applied", plan.summary())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manifestCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+	manifestCmd.AddCommand(manifestGenerateCmd)
+
+	manifestGenerateCmd.Flags().String("name", "team", "name of the manifest")
+	for _, cmd := range []*cobra.Command{planCmd, applyCmd} {
+		cmd.Flags().StringP("file", "f", "", "team manifest to apply")
+		cmd.MarkFlagRequired("file")
+		cmd.Flags().Bool("adopt", false, "take over existing profiles that wasp doesn't manage yet")
+	}
+	applyCmd.Flags().Bool("auto-approve", false, "apply without asking")
+}
+
+// manifestPlan is what applying a manifest changes. The changes to the
+// AWS config file are made to the loaded config and reported by Changes.
+type manifestPlan struct {
+	changes    []awsconfig.Change
+	sources    map[string]map[string]string
+	removed    []string
+	aliases    []aliasChange
+	skipped    []string
+	adopted    []string
+	collisions []waspconfig.Collision
+	// saveAliases is set when the wasp config's aliases or its record of
+	// the manifest's aliases changed
+	saveAliases bool
+}
+
+// aliasChange is an alias a manifest adds, points elsewhere or, with new
+// empty, removes
+type aliasChange struct {
+	alias, old, new string
+}
+
+// planFromFlags loads the manifest named by --file and plans applying it
+func planFromFlags(cmd *cobra.Command) (*manifestPlan, *awsconfig.ConfigFile, *waspconfig.Config, error) {
+	path, _ := cmd.Flags().GetString("file")
+	adopt, _ := cmd.Flags().GetBool("adopt")
+	m, err := manifest.Load(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	cf, err := loadConfigFile()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	wc, err := loadWaspConfig()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	plan, err := planManifest(cf, wc, m, adopt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return plan, cf, wc, nil
+}
+
+// planManifest makes cf and the wasp config's aliases match a manifest,
+// returning what changed
+func planManifest(cf *awsconfig.ConfigFile, wc *waspconfig.Config, m *manifest.Manifest, adopt bool) (*manifestPlan, error) {
+	plan := &manifestPlan{sources: make(map[string]map[string]string)}
+	mc := m.Config()
+
+	for _, s := range m.Sessions {
+		session := cf.SSOSession(s.Name)
+		session.StartURL = s.StartURL
+		session.Region = s.Region
+		if len(s.RegistrationScopes) > 0 {
+			session.RegistrationScopes = s.RegistrationScopes
+		}
+	}
+
+	planned, collisions, err := mc.ResolveNames(m.Roles(), profileTargets(cf))
+	if err != nil {
+		return nil, err
+	}
+	plan.collisions = collisions
+
+	// Like sync, leave profiles wasp doesn't manage alone unless adopting
+	// them, and never take profiles from another manifest
+	wanted := make(map[string]bool)
+	manage := func(name string, settings []waspconfig.Setting) bool {
+		wanted[name] = true
+		if existing, err := cf.GetProfile(name); err == nil {
+			switch {
+			case existing.Manifest != "" && existing.Manifest != m.Name:
+				plan.skipped = append(plan.skipped, fmt.Sprintf("%s (from manifest %s)", name, existing.Manifest))
+				return false
+			case !existing.Managed && !adopt:
+				plan.skipped = append(plan.skipped, name)
+				return false
+			case !existing.Managed:
+				plan.adopted = append(plan.adopted, name)
+			}
+		}
+		plan.sources[name] = make(map[string]string)
+		for _, setting := range settings {
+			plan.sources[name][setting.Key] = setting.Source
+		}
+		return true
+	}
+
+	for _, p := range planned {
+		if manage(p.Name, mc.Settings(p.Data)) {
+			generateProfile(cf, mc, p.Name, p.Data).Manifest = m.Name
+		}
+	}
+	for i, mp := range m.Profiles {
+		settings := m.Settings(mp)
+		if !manage(mp.Name, settings) {
+			continue
+		}
+		var profile *awsconfig.Profile
+		if mp.SSO() {
+			data := mp.Data(m)
+			profile = generateProfile(cf, mc, mp.Name, data)
+			planned = append(planned, waspconfig.Planned{Name: mp.Name, Data: data})
+		} else {
+			profile = cf.Profile(mp.Name)
+			cf.MoveProfile(mp.Name)
+			profile.Managed = true
+			profile.Generated = true
+			profile.SSOSession, profile.AccountID, profile.RoleName = "", "", ""
+			profile.RoleARN = mp.RoleARN
+			profile.SourceProfile = mp.SourceProfile
+			profile.CredentialSource = mp.CredentialSource
+			for _, key := range []string{"role_arn", "source_profile", "credential_source"} {
+				plan.sources[mp.Name][key] = fmt.Sprintf("profiles[%d]", i)
+			}
+		}
+		profile.Manifest = m.Name
+		profile.Settings = make(map[string]string)
+		for _, setting := range settings {
+			profile.Settings[setting.Key] = setting.Value
+		}
+	}
+
+	chained, err := mc.Chained(planned)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range chained {
+		if manage(ch.Name, ch.Settings) {
+			plan.sources[ch.Name]["role_arn"] = ch.Source
+			plan.sources[ch.Name]["source_profile"] = ch.Source
+			generateChainedProfile(cf, ch).Manifest = m.Name
+		}
+	}
+	for _, p := range cf.Profiles.List() {
+		if p.RoleARN != "" && p.Manifest == m.Name {
+			if _, err := cf.Chain(p.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, alias := range slices.Sorted(maps.Keys(m.Aliases)) {
+		if target := m.Aliases[alias]; wc.Aliases[alias] != target {
+			plan.aliases = append(plan.aliases, aliasChange{alias, wc.Aliases[alias], target})
+			if wc.Aliases == nil {
+				wc.Aliases = make(map[string]string)
+			}
+			wc.Aliases[alias] = target
+		}
+	}
+	// Aliases an earlier apply set that the manifest dropped, unless they've
+	// been pointed elsewhere since. Their materialized profiles go too.
+	applied := wc.ManifestAliases[m.Name]
+	for _, alias := range slices.Sorted(maps.Keys(applied)) {
+		target := applied[alias]
+		if _, ok := m.Aliases[alias]; ok || wc.Aliases[alias] != target {
+			continue
+		}
+		plan.aliases = append(plan.aliases, aliasChange{alias, target, ""})
+		delete(wc.Aliases, alias)
+		if p, err := cf.GetProfile(alias); err == nil && p.AliasFor == target {
+			plan.removed = append(plan.removed, alias)
+		}
+	}
+	if !maps.Equal(applied, m.Aliases) {
+		if wc.ManifestAliases == nil {
+			wc.ManifestAliases = make(map[string]map[string]string)
+		}
+		if len(m.Aliases) > 0 {
+			wc.ManifestAliases[m.Name] = maps.Clone(m.Aliases)
+		} else {
+			delete(wc.ManifestAliases, m.Name)
+		}
+		plan.saveAliases = true
+	}
+	plan.saveAliases = plan.saveAliases || len(plan.aliases) > 0
+
+	// Profiles an earlier apply created that have left the manifest
+	for _, p := range cf.Profiles.List() {
+		if p.Manifest == m.Name && !wanted[p.Name] {
+			plan.removed = append(plan.removed, p.Name)
+		}
+	}
+	slices.Sort(plan.removed)
+	plan.removed = slices.Compact(plan.removed)
+	for _, name := range plan.removed {
+		cf.DeleteProfile(name)
+	}
+	plan.changes = cf.Changes()
+	return plan, nil
+}
+
+func (p *manifestPlan) empty() bool {
+	return len(p.changes) == 0 && len(p.removed) == 0 && len(p.aliases) == 0
+}
+
+// summary counts the changes the way terraform does
+func (p *manifestPlan) summary() string {
+	add, change := 0, 0
+	for _, c := range p.changes {
+		if c.New {
+			add++
+		} else {
+			change++
+		}
+	}
+	remove := len(p.removed)
+	for _, a := range p.aliases {
+		switch {
+		case a.old == "":
+			add++
+		case a.new == "":
+			remove++
+		default:
+			change++
+		}
+	}
+	return fmt.Sprintf("%d to add, %d to change, %d to remove.", add, change, remove)
+}
+
+// print shows the plan like sync --dry-run, with removed profiles and
+// aliases after the changes
+func (p *manifestPlan) print(out io.Writer) error {
+	printCollisions(p.collisions)
+	if len(p.skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Left %d profiles alone; apply --adopt lets wasp manage hand-written ones:\n", len(p.skipped))
+		for _, name := range p.skipped {
+			fmt.Fprintln(os.Stderr, " ", name)
+		}
+	}
+	if len(p.adopted) > 0 {
+		fmt.Fprintf(os.Stderr, "Adopting %d profiles: %s\n", len(p.adopted), strings.Join(p.adopted, ", "))
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if p.empty() {
+		fmt.Fprintln(w, "No changes")
+		return w.Flush()
+	}
+	if len(p.changes) > 0 {
+		printChanges(w, p.changes, p.sources)
+	}
+	for _, name := range p.removed {
+		fmt.Fprintf(w, "- [profile %s]\n", name)
+	}
+	for _, a := range p.aliases {
+		if a.old == "" {
+			fmt.Fprintf(w, "+ alias %s = %s\n", a.alias, a.new)
+		} else if a.new == "" {
+			fmt.Fprintf(w, "- alias %s = %s\n", a.alias, a.old)
+		} else {
+			fmt.Fprintf(w, "~ alias %s = %s → %s\n", a.alias, a.old, a.new)
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %s\n", p.summary())
+	return w.Flush()
+}
@@ -0,0 +1,242 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/prompt"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/spf13/cobra"
+)
+
+// promptCmd represents the prompt command
+var promptCmd = &cobra.Command{
+	Use:   "prompt [profile|alias]",
+	Short: "Print a shell prompt segment for the current profile",
+	Long: `Prompt prints a short segment for a shell prompt: the current profile
+(AWS_PROFILE) or its alias, its tags and how long its SSO token has left.
+It prints nothing when no profile is set.
+
+Prompt is meant to run on every prompt, so it doesn't call AWS or parse the
+config files. It reads an index of profiles cached in
+~/.wasp/cache/prompt.json, which is built again once the AWS or wasp config
+files change, and the expiry of the cached SSO token.
+
+--format (or prompt.format in the wasp config) is a Go template with .Name,
+.Profile, .Alias, .AccountID, .AccountName, .Role, .Session, .Tags, .Color,
+.ExpiresAt and .Remaining; {{template "default" .}} is the default segment:
+
+  wasp prompt --format '{{.AccountName}}/{{.Role}}{{with .Remaining}} ⏳{{.}}{{end}}'
+
+--color writes the profile's tag color for a shell: ansi, bash or zsh, where
+bash and zsh mark the escapes so line editing isn't confused. 'wasp prompt
+init' prints the setup for bash, zsh, starship and powerlevel10k.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell, _ := cmd.Flags().GetString("color")
+		if !slices.Contains(prompt.Shells, prompt.Shell(shell)) {
+			return fmt.Errorf("unknown --color %q, expected one of %s", shell, joinShells(prompt.Shells))
+		}
+		name := os.Getenv("AWS_PROFILE")
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if name == "" {
+			return nil
+		}
+
+		idx, err := loadPromptIndex()
+		if err != nil {
+			return err
+		}
+		profile := idx.Resolve(name)
+		data := idx.Data(profile)
+		if key := idx.Profiles[profile].Token; key != "" {
+			var expiresAt time.Time
+			if path, err := ssocache.Path(key); err == nil {
+				if token, err := ssocache.Load(path); err == nil && token.AccessToken != "" {
+					expiresAt = token.ExpiresAt
+				}
+			}
+			data.SetExpiry(expiresAt, time.Now())
+		}
+
+		format := idx.Format
+		if cmd.Flags().Changed("format") {
+			format, _ = cmd.Flags().GetString("format")
+		}
+		tmpl, err := prompt.Parse(format)
+		if err != nil {
+			return err
+		}
+		text, err := prompt.Render(tmpl, data, prompt.Shell(shell))
+		if err != nil {
+			return err
+		}
+		fmt.Print(text)
+		return nil
+	},
+}
+
+var promptInitCmd = &cobra.Command{
+	Use:   "init <bash|zsh|starship|p10k>",
+	Short: "Print the setup for a shell prompt",
+	Long: `Init prints what to add to a shell or prompt config to show wasp prompt:
+
+  wasp prompt init bash >> ~/.bashrc
+  wasp prompt init zsh >> ~/.zshrc
+  wasp prompt init starship >> ~/.config/starship.toml
+  wasp prompt init p10k >> ~/.zshrc
+
+For powerlevel10k, also add wasp to POWERLEVEL9K_LEFT_PROMPT_ELEMENTS or
+POWERLEVEL9K_RIGHT_PROMPT_ELEMENTS.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "starship", "p10k"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snippet, ok := promptSnippets[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown prompt %q, expected bash, zsh, starship or p10k", args[0])
+		}
+		fmt.Print(snippet)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.AddCommand(promptInitCmd)
+
+	promptCmd.Flags().String("format", "", "Go template for the segment (default prompt.format or "+shellQuote(prompt.DefaultFormat)+")")
+	promptCmd.Flags().String("color", string(prompt.ShellNone), "write the tag color for a shell: "+joinShells(prompt.Shells))
+}
+
+// promptSnippets set up each prompt to run wasp prompt
+var promptSnippets = map[string]string{
+	"bash": `# wasp prompt segment
+__wasp_prompt() {
+  local segment
+  segment=$(wasp prompt --color bash 2>/dev/null) && [ -n "$segment" ] && printf '%s ' "$segment"
+}
+PS1='$(__wasp_prompt)'"$PS1"
+`,
+	"zsh": `# wasp prompt segment
+setopt prompt_subst
+__wasp_prompt() {
+  local segment
+  segment=$(wasp prompt --color zsh 2>/dev/null) && [[ -n $segment ]] && print -rn -- "$segment "
+}
+PROMPT='$(__wasp_prompt)'"$PROMPT"
+`,
+	"starship": `# wasp prompt segment
+[custom.wasp]
+command = "wasp prompt --color ansi"
+when = "test -n \"$AWS_PROFILE\""
+format = "$output "
+`,
+	"p10k": `# wasp prompt segment; add wasp to POWERLEVEL9K_*_PROMPT_ELEMENTS
+function prompt_wasp() {
+  [[ -n $AWS_PROFILE ]] || return
+  local out
+  out=$(wasp prompt --format '{{.Color}} {{template "default" .}}' 2>/dev/null) || return
+  local color=${out%% *} text=${out#* }
+  [[ -n $text ]] || return
+  local -a fg
+  [[ -n $color ]] && fg=(-f $color)
+  p10k segment $fg -i '🐝' -t "$text"
+}
+`,
+}
+
+// loadPromptIndex reads the cached prompt index, building it again if the
+// AWS or wasp config files changed since it was written
+func loadPromptIndex() (*prompt.Index, error) {
+	path, err := waspConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if idx, err := prompt.Load(); err == nil && idx.Fresh(path) {
+		return idx, nil
+	}
+	return buildPromptIndex(path)
+}
+
+// buildPromptIndex indexes every profile in the AWS config file and saves
+// the index
+func buildPromptIndex(path string) (*prompt.Index, error) {
+	wc, err := waspconfig.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if wc.Files.Managed == "" {
+		files = append(files, config.DefaultSharedConfigFilename())
+	} else {
+		files = append(append(files, wc.Files.ManagedPath()), wc.Files.SourcePaths()...)
+	}
+	// Stamp the files before reading them, so a change made meanwhile
+	// builds the index again next time
+	stamps := prompt.Stamps(files...)
+
+	cf, err := loadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	fillAccountNames(cf)
+
+	idx, err := prompt.New()
+	if err != nil {
+		return nil, err
+	}
+	idx.Config = path
+	idx.Format = wc.Prompt.Format
+	idx.Files = stamps
+	idx.Aliases = wc.Aliases
+	for _, p := range cf.Profiles.List() {
+		data := profileData(cf, p)
+		tags := profileTags(cf, wc, p)
+		idx.Profiles[p.Name] = prompt.Entry{
+			AccountID:   data.AccountID,
+			AccountName: data.AccountName,
+			Role:        data.RoleName,
+			Session:     data.Session,
+			Token:       tokenKey(cf, p),
+			Tags:        tags,
+			Color:       wc.UI.TagColor(tags),
+		}
+	}
+	if err := idx.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to save the prompt index:", err)
+	}
+	return idx, nil
+}
+
+// tokenKey returns the SSO token cache key of the session a profile signs
+// in with, following source_profile back to an SSO profile
+func tokenKey(cf *awsconfig.ConfigFile, p *awsconfig.Profile) string {
+	chain, _ := cf.Chain(p.Name)
+	if len(chain) == 0 {
+		return ""
+	}
+	root := chain[len(chain)-1]
+	if root.SSOSession != "" {
+		return root.SSOSession
+	}
+	return cf.ProfileKeys(root.Name)["sso_start_url"]
+}
+
+func joinShells(shells []prompt.Shell) string {
+	names := make([]string, len(shells))
+	for i, shell := range shells {
+		names[i] = string(shell)
+	}
+	return strings.Join(names, ", ")
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var cfgFile string
@@ -20,6 +22,8 @@ var rootCmd = &cobra.Command{
 	Long: `Wasp is a CLI tool to manage AWS configuration profiles. This
 manages profiles in an AWS config file based on the AWS SSO session.`,
 	Version: "0.0.0",
+	// Errors from RunE are runtime failures, not usage mistakes
+	SilenceUsage: true,
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
@@ -38,39 +42,76 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.wasp/config.yaml)")
-
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+// loadConfigFile loads the AWS config file that wasp manages, or the files
+// it's composed from when the wasp config sets files.managed
+func loadConfigFile() (*awsconfig.ConfigFile, error) {
+	wc, err := loadWaspConfig()
+	if err != nil {
+		return nil, err
+	}
+	if wc.Files.Managed == "" {
+		return awsconfig.NewFromConfig(config.DefaultSharedConfigFilename())
+	}
+	return awsconfig.NewFromFiles(wc.Files.ManagedPath(), wc.Files.SourcePaths()...)
+}
 
-		// Search config in home directory with name ".wasp".
-		viper.AddConfigPath(home + "/.wasp")
-		viper.SetConfigType("yaml")
-		viper.SetConfigName("config")
+// saveConfigFile writes changed profiles back to their files and composes
+// the AWS config file again if wasp keeps its profiles in a file of its own.
+// Nothing is written if the AWS config file can't be composed.
+func saveConfigFile(cf *awsconfig.ConfigFile) error {
+	wc, err := loadWaspConfig()
+	if err != nil {
+		return err
+	}
+	if wc.Files.Managed != "" {
+		dst, sources := composeFiles(wc)
+		if err := awsconfig.CheckCompose(dst, sources...); err != nil {
+			return fmt.Errorf("%w (see wasp compose --help)", err)
+		}
+	}
+	if err := cf.Update(); err != nil {
+		return err
 	}
+	if wc.Files.Managed == "" {
+		return nil
+	}
+	_, err = composeConfigFile(wc)
+	return err
+}
+
+// composeFiles returns the file to compose and the files it's composed from
+func composeFiles(wc *waspconfig.Config) (string, []string) {
+	return wc.Files.ComposePath(config.DefaultSharedConfigFilename()), append(wc.Files.SourcePaths(), wc.Files.ManagedPath())
+}
+
+// composeConfigFile concatenates the source files and the managed file into
+// the composed file, returning its path
+func composeConfigFile(wc *waspconfig.Config) (string, error) {
+	dst, sources := composeFiles(wc)
+	return dst, awsconfig.Compose(dst, sources...)
+}
 
-	viper.AutomaticEnv() // read in environment variables that match
+// waspConfigPath returns the wasp config file named by --config, or the
+// default one
+func waspConfigPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	return waspconfig.DefaultPath()
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+// loadWaspConfig loads the wasp config file
+func loadWaspConfig() (*waspconfig.Config, error) {
+	path, err := waspConfigPath()
+	if err != nil {
+		return nil, err
 	}
+	return waspconfig.Load(path)
 }
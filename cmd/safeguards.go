@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"github.com/spf13/cobra"
+)
+
+// profileData returns what rules see for a profile. Role profiles are the
+// account and role they assume, reached from the SSO session at the root
+// of their source_profile chain.
+func profileData(cf *awsconfig.ConfigFile, p *awsconfig.Profile) waspconfig.NameData {
+	if chain, ok := profileChain(cf, p); ok {
+		return waspconfig.NameData{
+			Session:     chain.session,
+			AccountID:   chain.accountID,
+			AccountName: chain.accountName,
+			RoleName:    chain.roles[len(chain.roles)-1],
+		}
+	}
+	if p.RoleARN != "" {
+		accountID, roleName, _ := awsconfig.ParseRoleARN(p.RoleARN)
+		return waspconfig.NameData{AccountID: accountID, AccountName: p.AccountName, RoleName: roleName}
+	}
+	return nameDataFor(p)
+}
+
+// profileTags returns the tags the rules give a profile and every profile
+// it gets credentials from, so a role assumed from a prod profile is prod
+// as well
+func profileTags(cf *awsconfig.ConfigFile, wc *waspconfig.Config, p *awsconfig.Profile) []string {
+	chain, _ := cf.Chain(p.Name)
+	if len(chain) == 0 {
+		chain = []*awsconfig.Profile{p}
+	}
+	var tags []string
+	for _, link := range chain {
+		tags = append(tags, wc.Tags(profileData(cf, link))...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// tagStyle colors a profile by its tags
+func tagStyle(wc *waspconfig.Config, tags []string) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if color := wc.UI.TagColor(tags); color != "" {
+		style = style.Foreground(lipgloss.Color(color))
+	}
+	return style
+}
+
+// tagEnv returns the variables that tell shell prompts about a profile's
+// tags. Empty values are to be unset.
+func tagEnv(wc *waspconfig.Config, tags []string) [][2]string {
+	return [][2]string{
+		{"WASP_PROFILE_TAGS", strings.Join(tags, ",")},
+		{"WASP_PROMPT_COLOR", wc.UI.TagColor(tags)},
+	}
+}
+
+// confirmProfile makes the user type the account name of a prod or
+// sensitive profile before it's used. Scripts can give the name ahead of
+// time with --confirm.
+func confirmProfile(cmd *cobra.Command, cf *awsconfig.ConfigFile, wc *waspconfig.Config, p *awsconfig.Profile) error {
+	tags := profileTags(cf, wc, p)
+	if !waspconfig.Sensitive(tags) {
+		return nil
+	}
+	data := profileData(cf, p)
+	account := accountLabel(data.AccountName, data.AccountID)
+	matches := func(answer string) bool {
+		answer = strings.TrimSpace(answer)
+		return answer != "" && (answer == data.AccountName || answer == data.AccountID)
+	}
+
+	if given, _ := cmd.Flags().GetString("confirm"); given != "" {
+		if !matches(given) {
+			return fmt.Errorf("--confirm %q doesn't match %s, the account of %s", given, account, p.Name)
+		}
+		return nil
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("%s is tagged %s; pass --confirm with its account name to use it", p.Name, strings.Join(tags, ", "))
+	}
+
+	what := "name"
+	if data.AccountName == "" {
+		what = "ID"
+	}
+	warning := tagStyle(wc, tags).Bold(true)
+	lipgloss.Fprintf(os.Stderr, "%s is a %s profile for %s.\nType the account %s to continue: ",
+		warning.Render(p.Name), warning.Render(strings.Join(tags, "/")), account, what)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil || !matches(answer) {
+		return fmt.Errorf("account %s didn't match; not using %s", what, p.Name)
+	}
+	return nil
+}
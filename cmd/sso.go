@@ -0,0 +1,187 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/buzzsurfr/wasp/internal/accountcache"
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+)
+
+// ssoClients holds the AWS clients used to talk to a single SSO session
+type ssoClients struct {
+	session   *awsconfig.SSOSession
+	sso       *sso.Client
+	oidc      *ssooidc.Client
+	tokenPath string
+}
+
+// ssoAccount is an AWS account and the roles the SSO user can assume in it
+type ssoAccount struct {
+	ID    string
+	Name  string
+	Email string
+	Roles []string
+}
+
+func newSSOClients(ctx context.Context, session *awsconfig.SSOSession) (*ssoClients, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Region = session.Region
+
+	tokenPath, err := ssocache.Path(session.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssoClients{
+		session:   session,
+		sso:       sso.NewFromConfig(cfg),
+		oidc:      ssooidc.NewFromConfig(cfg),
+		tokenPath: tokenPath,
+	}, nil
+}
+
+// accessToken returns a usable access token for the session. An expired
+// token is refreshed silently with the cached refresh token when possible;
+// only if that fails does the user have to go through the browser login.
+func (c *ssoClients) accessToken(ctx context.Context) (*ssocache.Token, error) {
+	now := time.Now()
+	token, err := ssocache.Load(c.tokenPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if token != nil && !token.Expired(now) {
+		return token, nil
+	}
+
+	if token != nil && token.CanRefresh(now) {
+		err := token.Refresh(ctx, c.oidc, now)
+		if err == nil {
+			if err := token.Save(c.tokenPath); err != nil {
+				return nil, err
+			}
+			return token, nil
+		}
+		fmt.Fprintf(os.Stderr, "Unable to refresh %s SSO session: %v\n", c.session.Name, err)
+	}
+
+	return c.login()
+}
+
+// login runs the interactive AWS CLI login and returns the new token
+func (c *ssoClients) login() (*ssocache.Token, error) {
+	fmt.Fprintf(os.Stderr, "Attempting to login to %s SSO session.\n", c.session.Name)
+	cmd := exec.Command("aws", "sso", "login", "--sso-session", c.session.Name)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("unauthorized. Please run `aws sso login --sso-session %s` to refresh your session", c.session.Name)
+	}
+	return ssocache.Load(c.tokenPath)
+}
+
+// accounts lists every account and role available to the session. If the
+// token is rejected the user is logged in again and the listing retried once.
+func (c *ssoClients) accounts(ctx context.Context) ([]ssoAccount, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := c.listAccounts(ctx, token.AccessToken)
+	var aerr *types.UnauthorizedException
+	if errors.As(err, &aerr) {
+		if token, err = c.login(); err != nil {
+			return nil, err
+		}
+		accounts, err = c.listAccounts(ctx, token.AccessToken)
+	}
+	return accounts, err
+}
+
+func (c *ssoClients) listAccounts(ctx context.Context, accessToken string) ([]ssoAccount, error) {
+	var accounts []ssoAccount
+	accountPages := sso.NewListAccountsPaginator(c.sso, &sso.ListAccountsInput{
+		AccessToken: aws.String(accessToken),
+	})
+	for accountPages.HasMorePages() {
+		page, err := accountPages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range page.AccountList {
+			a := ssoAccount{
+				ID:    aws.ToString(account.AccountId),
+				Name:  aws.ToString(account.AccountName),
+				Email: aws.ToString(account.EmailAddress),
+			}
+
+			rolePages := sso.NewListAccountRolesPaginator(c.sso, &sso.ListAccountRolesInput{
+				AccessToken: aws.String(accessToken),
+				AccountId:   account.AccountId,
+			})
+			for rolePages.HasMorePages() {
+				rolePage, err := rolePages.NextPage(ctx)
+				if err != nil {
+					return nil, err
+				}
+				for _, role := range rolePage.RoleList {
+					a.Roles = append(a.Roles, aws.ToString(role.RoleName))
+				}
+			}
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+// cacheAccounts records the accounts discovered for a session so their
+// names can be shown without calling AWS SSO.
+func cacheAccounts(session string, accounts []ssoAccount) error {
+	c := &accountcache.Cache{UpdatedAt: time.Now()}
+	for _, a := range accounts {
+		c.Accounts = append(c.Accounts, accountcache.Account{ID: a.ID, Name: a.Name, Email: a.Email})
+	}
+	return c.Save(session)
+}
+
+// fillAccountNames fills in missing account names on SSO profiles from the
+// account cache.
+func fillAccountNames(cf *awsconfig.ConfigFile) {
+	caches := make(map[string]*accountcache.Cache)
+	for _, profile := range cf.Profiles.List() {
+		if profile.AccountName != "" || profile.SSOSession == "" || profile.AccountID == "" {
+			continue
+		}
+		c, ok := caches[profile.SSOSession]
+		if !ok {
+			var err error
+			if c, err = accountcache.Load(profile.SSOSession); err != nil {
+				c = &accountcache.Cache{}
+			}
+			caches[profile.SSOSession] = c
+		}
+		if account, ok := c.Lookup(profile.AccountID); ok {
+			profile.AccountName = account.Name
+			profile.AccountEmail = account.Email
+		}
+	}
+	cf.Profiles.UpdateColWidths()
+}
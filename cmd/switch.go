@@ -6,9 +6,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/state"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
 	"charm.land/bubbles/v2/table"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
@@ -18,12 +22,21 @@ import (
 
 // switchCmd represents the switch command
 var switchCmd = &cobra.Command{
-	Use:     "switch",
+	Use:     "switch [profile|alias]",
 	Aliases: []string{"sw", "swap"},
 	Short:   "Change your current AWS profile",
 	Long: `Switch will change your current AWS profile. This will change the
 current profile in the AWS_PROFILE and AWS_DEFAULT_PROFILE environment
-variables.`,
+variables.
+
+Given a profile name or alias, switch changes to it without showing the
+picker.
+
+Profiles tagged prod or sensitive by the wasp config's rules are colored
+in the picker, and switch asks for their account name before changing to
+them; --confirm gives it ahead of time. WASP_PROFILE_TAGS and
+WASP_PROMPT_COLOR are set for shell prompts.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		baseStyle = lipgloss.NewStyle().
 			BorderStyle(lipgloss.HiddenBorder()).
@@ -41,34 +54,198 @@ variables.`,
 			Bold(false)
 
 		// Load AWS config file
-		cf, err := awsconfig.NewFromConfig(config.DefaultSharedConfigFilename())
+		cf, err := loadConfigFile()
 		if err != nil {
 			panic(err)
 		}
+		fillAccountNames(cf)
 
-		// Create Bubbles table for profiles
-		t := cf.Profiles.TableModel(10)
-		t.SetColumns(cf.Profiles.TableColumns())
-		t.Focus()
-		t.SetStyles(tableStyle)
+		st, err := state.Load()
+		cobra.CheckErr(err)
+		wc, err := loadWaspConfig()
+		cobra.CheckErr(err)
 
 		// Choose a profile
-		p := tea.NewProgram(newProfileModel(t, cf.Profiles.TableColumns()), tea.WithOutput(os.Stderr))
-		m, err := p.Run()
-		if err != nil {
-			fmt.Println("Error running program:", err)
-			os.Exit(1)
-		}
-		if m, ok := m.(profileModel); ok && cf.HasProfile(m.profileName) {
-			fmt.Printf("export AWS_PROFILE=%s\n", m.profileName)
+		var profileName string
+		if len(args) == 1 {
+			profile, err := resolveProfile(cf, wc, args[0])
+			cobra.CheckErr(err)
+			profileName = profile.Name
+		} else if useTree(cmd, wc) {
+			p := tea.NewProgram(newTreeModel("AWS profiles:", profileTree(cf, wc, cf.Profiles.List()), wc.UI.Rows(15)), tea.WithOutput(os.Stderr))
+			m, err := p.Run()
+			if err != nil {
+				fmt.Println("Error running program:", err)
+				os.Exit(1)
+			}
+			if m, ok := m.(treeModel); ok && m.selected != nil {
+				profileName = m.selected[0]
+			}
 		} else {
+			// Create Bubbles table for profiles, favorites and recently used first
+			columns := append([]table.Column{{Title: "★", Width: 1}}, cf.Profiles.TableColumns()...)
+			rows, names := switchRows(cf, wc, st)
+			fitColumns(columns, rows)
+			t := table.New(
+				table.WithColumns(columns),
+				table.WithRows(rows),
+				table.WithHeight(min(len(rows), wc.UI.Rows(10))),
+			)
+			t.Focus()
+			t.SetStyles(tableStyle)
+
+			p := tea.NewProgram(newProfileModel(t, columns, cf, wc, st, names), tea.WithOutput(os.Stderr))
+			m, err := p.Run()
+			if err != nil {
+				fmt.Println("Error running program:", err)
+				os.Exit(1)
+			}
+			if m, ok := m.(profileModel); ok {
+				profileName = m.profileName
+			}
+		}
+		// Favorites starred in the picker are saved even without a switch
+		profile := cf.Profiles.Name(profileName)
+		if profile != nil {
+			err = confirmProfile(cmd, cf, wc, profile)
+			if err == nil {
+				st.Record(profileName, time.Now())
+			}
+		}
+		if err := st.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to save wasp state:", err)
+		}
+		cobra.CheckErr(err)
+		if profile == nil {
 			os.Exit(1)
 		}
+		fmt.Printf("export AWS_PROFILE=%s\n", shellQuote(profileName))
+		for _, env := range tagEnv(wc, profileTags(cf, wc, profile)) {
+			if env[1] == "" {
+				fmt.Printf("unset %s\n", env[0])
+			} else {
+				fmt.Printf("export %s=%s\n", env[0], shellQuote(env[1]))
+			}
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(switchCmd)
+
+	switchCmd.Flags().Bool("tree", false, "group profiles by SSO session and account")
+	switchCmd.Flags().String("confirm", "", "account name of a prod or sensitive profile, instead of typing it")
+}
+
+// useTree reports whether to show the tree view: --tree if given, or the
+// ui.tree preference
+func useTree(cmd *cobra.Command, wc *waspconfig.Config) bool {
+	if cmd.Flags().Changed("tree") {
+		tree, _ := cmd.Flags().GetBool("tree")
+		return tree
+	}
+	return wc.UI.Tree
+}
+
+// profileTree groups SSO profiles by session and account, with a role
+// leaf for each profile, colored by its tags. Role profiles chained from
+// an SSO profile go with the account they assume a role in. Other profiles
+// are listed together.
+func profileTree(cf *awsconfig.ConfigFile, wc *waspconfig.Config, profiles []*awsconfig.Profile) []*treeNode {
+	var leaves []treeLeaf
+	for _, p := range profiles {
+		style := tagStyle(wc, profileTags(cf, wc, p))
+		if chain, ok := profileChain(cf, p); ok {
+			leaves = append(leaves, treeLeaf{
+				path:  []string{chain.session, accountLabel(chain.accountName, chain.accountID)},
+				label: fmt.Sprintf("%s → %s", strings.Join(chain.roles, " ⇒ "), p.Name),
+				style: style,
+				row:   table.Row{p.Name},
+			})
+			continue
+		}
+		if p.SSOSession == "" {
+			leaves = append(leaves, treeLeaf{
+				path:  []string{"Other profiles"},
+				label: p.Name,
+				style: style,
+				row:   table.Row{p.Name},
+			})
+			continue
+		}
+		leaves = append(leaves, treeLeaf{
+			path:  []string{p.SSOSession, accountLabel(p.AccountName, p.AccountID)},
+			label: fmt.Sprintf("%s → %s", p.RoleName, p.Name),
+			style: style,
+			row:   table.Row{p.Name},
+		})
+	}
+	return buildTree(leaves)
+}
+
+// roleChain is how a role profile is reached from the profile at the root
+// of its source_profile chain
+type roleChain struct {
+	session     string
+	accountID   string
+	accountName string
+	// roles are the roles assumed, starting with the root's SSO role
+	roles []string
+}
+
+// profileChain follows a role profile's source_profile chain back to an
+// SSO profile
+func profileChain(cf *awsconfig.ConfigFile, p *awsconfig.Profile) (roleChain, bool) {
+	if p.RoleARN == "" {
+		return roleChain{}, false
+	}
+	chain, err := cf.Chain(p.Name)
+	if err != nil || chain[len(chain)-1].SSOSession == "" {
+		return roleChain{}, false
+	}
+	root := chain[len(chain)-1]
+	accountID, _, _ := awsconfig.ParseRoleARN(p.RoleARN)
+	rc := roleChain{session: root.SSOSession, accountID: accountID, accountName: p.AccountName, roles: []string{root.RoleName}}
+	if rc.accountName == "" && accountID == root.AccountID {
+		rc.accountName = root.AccountName
+	}
+	for i := len(chain) - 2; i >= 0; i-- {
+		_, role, _ := awsconfig.ParseRoleARN(chain[i].RoleARN)
+		rc.roles = append(rc.roles, role)
+	}
+	return rc, true
+}
+
+// profileRow is a profile's table row, showing role profiles with the SSO
+// session they're reached from and the roles assumed on the way
+func profileRow(cf *awsconfig.ConfigFile, p *awsconfig.Profile) table.Row {
+	row := p.TableRow()
+	if chain, ok := profileChain(cf, p); ok {
+		row[1], row[2], row[3], row[4] = chain.accountName, chain.session, chain.accountID, strings.Join(chain.roles, " ⇒ ")
+	}
+	return row
+}
+
+// fitColumns widens columns to fit their rows
+func fitColumns(columns []table.Column, rows []table.Row) {
+	for _, row := range rows {
+		for i, cell := range row {
+			columns[i].Width = max(columns[i].Width, lipgloss.Width(cell))
+		}
+	}
+}
+
+var helpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+// shellQuote quotes a value for use in a POSIX shell command
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '@' ||
+			'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 type profileModel struct {
@@ -76,15 +253,51 @@ type profileModel struct {
 	table       table.Model
 	columns     []table.Column
 	selected    table.Row
-	quitting    bool
+	configFile  *awsconfig.ConfigFile
+	waspConfig  *waspconfig.Config
+	state       *state.State
+	// names are the profile names of the rows, whose cells may be colored
+	names    []string
+	quitting bool
 }
 
-func newProfileModel(t table.Model, columns []table.Column) profileModel {
+func newProfileModel(t table.Model, columns []table.Column, cf *awsconfig.ConfigFile, wc *waspconfig.Config, st *state.State, names []string) profileModel {
 	return profileModel{
-		table:    t,
-		columns:  columns,
-		quitting: false,
+		table:      t,
+		columns:    columns,
+		configFile: cf,
+		waspConfig: wc,
+		state:      st,
+		names:      names,
+		quitting:   false,
+	}
+}
+
+// switchRows builds the switch table rows with a favorite marker in front,
+// ordered favorites first and then by how recently they were used, and
+// colored by tag. It returns the profile name of each row as well.
+func switchRows(cf *awsconfig.ConfigFile, wc *waspconfig.Config, st *state.State) ([]table.Row, []string) {
+	var names []string
+	for name := range cf.Profiles.Map() {
+		names = append(names, name)
+	}
+	st.Sort(names)
+
+	var rows []table.Row
+	for _, name := range names {
+		star := ""
+		if st.IsFavorite(name) {
+			star = "★"
+		}
+		p := cf.Profiles.Name(name)
+		row := profileRow(cf, p)
+		style := tagStyle(wc, profileTags(cf, wc, p))
+		for i := range row {
+			row[i] = style.Render(row[i])
+		}
+		rows = append(rows, append(table.Row{star}, row...))
 	}
+	return rows, names
 }
 
 func (m profileModel) Init() tea.Cmd {
@@ -102,9 +315,24 @@ func (m profileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		case "enter":
 			m.selected = m.table.SelectedRow()
-			m.profileName = m.selected[0]
+			if m.selected == nil {
+				return m, nil
+			}
+			m.profileName = m.names[m.table.Cursor()]
 			m.quitting = true
 			return m, tea.Quit
+		case "*", "s":
+			// Star the profile and keep the cursor on it as it moves
+			if m.table.SelectedRow() == nil {
+				return m, nil
+			}
+			name := m.names[m.table.Cursor()]
+			m.state.ToggleFavorite(name)
+			rows, names := switchRows(m.configFile, m.waspConfig, m.state)
+			m.table.SetRows(rows)
+			m.names = names
+			m.table.SetCursor(slices.Index(names, name))
+			return m, nil
 		}
 	}
 
@@ -116,5 +344,5 @@ func (m profileModel) View() tea.View {
 	if m.quitting {
 		return tea.NewView("")
 	}
-	return tea.NewView(m.table.View() + "\n\n\n")
+	return tea.NewView(m.table.View() + "\n" + helpStyle.Render("enter switch • s/* star • q quit") + "\n\n")
 }
@@ -5,20 +5,21 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
 	"github.com/aws/aws-sdk-go-v2/service/sso"
-	"github.com/aws/aws-sdk-go-v2/service/sso/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
 	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
 	tea "charm.land/bubbletea/v2"
 	"github.com/spf13/cobra"
 )
@@ -28,96 +29,150 @@ var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Synchronize AWS profiles based on SSO sessions",
 	Long: `Sync will synchronize AWS profiles based on SSO sessions. This will
-create or update profiles based on the SSO sessions found in the AWS config file.`,
+create or update profiles based on the SSO sessions found in the AWS config file.
+
+Filters in the wasp config decide which accounts and roles get profiles;
+--explain shows the decision for every account role. Defaults and rules
+in the wasp config add keys such as region to the generated profiles;
+--dry-run shows the keys sync would write and which rule set them, and the
+keys it would remove because no rule sets them any more. Neither --explain
+nor --dry-run changes any files, though listing the accounts still signs
+in to AWS SSO if the session's token has expired.
+
+Account roles whose profile names collide, with each other or with a
+profile that signs in elsewhere, are renamed by the naming.collisions
+strategy and reported.
+
+Chains in the wasp config add profiles that assume another role, such as
+OrganizationAccountAccessRole, from the SSO profiles they match.
+
+Sync only changes profiles wasp manages, marked with wasp_managed = true.
+Existing profiles for the same account role are left alone unless
+--adopt is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		// Load AWS config file
-		cf, err := awsconfig.NewFromConfig(config.DefaultSharedConfigFilename())
+		cf, err := loadConfigFile()
 		if err != nil {
 			panic(err)
 		}
+		wc, err := loadWaspConfig()
+		cobra.CheckErr(err)
+		cobra.CheckErr(wc.Validate())
+
+		sessions, err := syncSessions(cf, wc)
+		cobra.CheckErr(err)
+
+		explain, _ := cmd.Flags().GetBool("explain")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		var roles []waspconfig.NameData
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if explain {
+			fmt.Fprintln(w, "SESSION\tACCOUNT\tROLE\tRESULT\tREASON")
+		}
 
-		// For each SSO session in config file
-		for _, session := range cf.SSOSessions.Map() {
+		// For each SSO session to sync
+		for _, session := range sessions {
 
-			cfg, err := config.LoadDefaultConfig(context.Background())
-			if err != nil {
-				panic(err)
-			}
-			cfg.Region = session.Region
+			ctx := context.Background()
+			clients, err := newSSOClients(ctx, session)
+			cobra.CheckErr(err)
 
-			ssoClient := sso.NewFromConfig(cfg)
-			ssoOidcClient := ssooidc.NewFromConfig(cfg)
-			tokenPath, err := ssocreds.StandardCachedTokenFilepath(session.Name)
-			if err != nil {
-				panic(err)
+			// List associated AWS accounts and roles
+			accounts, err := clients.accounts(ctx)
+			cobra.CheckErr(err)
+			if !explain && !dryRun {
+				cobra.CheckErr(cacheAccounts(session.Name, accounts))
 			}
 
-			// Read JSON from tokenPath and parse sso_start_url
-			var ssoData SSOData
-			tokenFile, err := os.ReadFile(tokenPath)
-			if err == nil {
-				err = json.Unmarshal(tokenFile, &ssoData)
-				if err != nil {
-					panic(err)
+			for _, account := range accounts {
+				for _, role := range account.Roles {
+					data := nameData(session, account, role)
+					decision := wc.Session(session.Name).Decide(data)
+					if explain {
+						result := "skip"
+						if decision.Allowed {
+							result = "sync"
+						}
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", session.Name, accountLabel(account.Name, account.ID), role, result, decision.Reason)
+						continue
+					}
+					if decision.Allowed {
+						roles = append(roles, data)
+					}
 				}
-			} else if err != nil && !errors.Is(err, os.ErrNotExist) {
-				panic(err)
 			}
+		}
 
-			ssoTokenProvider := ssocreds.NewSSOTokenProvider(ssoOidcClient, tokenPath)
-
-			var provider aws.CredentialsProvider
-			provider = ssocreds.New(ssoClient, "123456789012", "no-role", session.StartURL, func(options *ssocreds.Options) {
-				options.SSOTokenProvider = ssoTokenProvider
-			})
-
-			// Wrap the provider with aws.CredentialsCache to cache the credentials until their expire time
-			provider = aws.NewCredentialsCache(provider)
+		if explain {
+			cobra.CheckErr(w.Flush())
+			return
+		}
 
-			// List associated AWS accounts and roles
-			var listAccounts *sso.ListAccountsOutput
-			for retries := 0; retries < 2; retries++ {
-				listAccounts, err = ssoClient.ListAccounts(context.TODO(), &sso.ListAccountsInput{
-					AccessToken: &ssoData.AccessToken,
-				})
-				if err != nil {
-					var aerr *types.UnauthorizedException
-					if errors.As(err, &aerr) {
-						fmt.Printf("Unauthorized. Attempting to login to %s SSO session.\n", session.Name)
-						cmd := exec.Command("aws", "sso", "login", "--sso-session", session.Name)
-						if err := cmd.Run(); err != nil {
-							fmt.Printf("Unauthorized. Please run `aws sso login --sso-session %s` to refresh your session.\n", session.Name)
-							os.Exit(1)
-						} else {
-							continue
-						}
-					} else {
-						panic(err)
-					}
+		// Name the profiles, renaming any that collide
+		planned, collisions, err := wc.ResolveNames(roles, profileTargets(cf))
+		cobra.CheckErr(err)
+		printCollisions(collisions)
+
+		// Update profiles in AWS config file, leaving hand-written ones alone
+		// unless adopting them
+		adopt, _ := cmd.Flags().GetBool("adopt")
+		var skipped, adopted []string
+		sources := make(map[string]map[string]string)
+		manage := func(name string, settings []waspconfig.Setting) bool {
+			if existing, err := cf.GetProfile(name); err == nil && !existing.Managed {
+				if !adopt {
+					skipped = append(skipped, name)
+					return false
 				}
+				adopted = append(adopted, name)
+			}
+			sources[name] = make(map[string]string)
+			for _, setting := range settings {
+				sources[name][setting.Key] = setting.Source
 			}
+			return true
+		}
+		for _, p := range planned {
+			if manage(p.Name, wc.Settings(p.Data)) {
+				generateProfile(cf, wc, p.Name, p.Data)
+			}
+		}
 
-			for _, account := range listAccounts.AccountList {
-				// Account Roles
-				listAccountRoles, err := ssoClient.ListAccountRoles(context.TODO(), &sso.ListAccountRolesInput{
-					AccessToken: &ssoData.AccessToken,
-					AccountId:   account.AccountId,
-				})
-				if err != nil {
-					panic(err)
-				}
-				for _, role := range listAccountRoles.RoleList {
-					// Update profile in AWS config file
-					profile := cf.Profile(fmt.Sprintf("%s_%s", aws.ToString(account.AccountName), aws.ToString(role.RoleName)))
-					profile.SSOSession = session.Name
-					profile.AccountID = aws.ToString(account.AccountId)
-					profile.RoleName = aws.ToString(role.RoleName)
-				}
+		// Chain profiles that assume roles from the SSO profiles, making sure
+		// every chain leads back to credentials before writing any
+		chained, err := wc.Chained(planned)
+		cobra.CheckErr(err)
+		for _, ch := range chained {
+			if manage(ch.Name, ch.Settings) {
+				sources[ch.Name]["role_arn"] = ch.Source
+				sources[ch.Name]["source_profile"] = ch.Source
+				generateChainedProfile(cf, ch)
+				_, err := cf.Chain(ch.Name)
+				cobra.CheckErr(err)
+			}
+		}
+		if len(skipped) > 0 {
+			fmt.Fprintf(os.Stderr, "Left %d profiles wasp doesn't manage alone; sync --adopt lets wasp manage them:\n", len(skipped))
+			for _, name := range skipped {
+				fmt.Fprintln(os.Stderr, " ", name)
+			}
+		}
+		if len(adopted) > 0 {
+			verb := "Adopted"
+			if dryRun {
+				verb = "Would adopt"
 			}
+			fmt.Fprintf(os.Stderr, "%s %d profiles: %s\n", verb, len(adopted), strings.Join(adopted, ", "))
 		}
 
-		err = cf.Update()
+		if dryRun {
+			printChanges(w, cf.Changes(), sources)
+			cobra.CheckErr(w.Flush())
+			return
+		}
+
+		err = saveConfigFile(cf)
 		if err != nil {
 			panic(err)
 		}
@@ -126,6 +181,150 @@ create or update profiles based on the SSO sessions found in the AWS config file
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().Bool("explain", false, "show which account roles the filters sync or skip, and why, without changing anything")
+	syncCmd.Flags().Bool("adopt", false, "take over existing profiles that wasp doesn't manage yet")
+	syncCmd.Flags().Bool("dry-run", false, "show the profile keys sync would write, and which rule set them, without changing anything")
+}
+
+// syncSessions returns the SSO sessions to sync: the ones listed in the
+// wasp config, or else every session in the AWS config file
+func syncSessions(cf *awsconfig.ConfigFile, wc *waspconfig.Config) ([]*awsconfig.SSOSession, error) {
+	var sessions []*awsconfig.SSOSession
+	if len(wc.Sessions) == 0 {
+		for _, session := range cf.SSOSessions.Map() {
+			sessions = append(sessions, session)
+		}
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].Name < sessions[j].Name
+		})
+		return sessions, nil
+	}
+	for _, s := range wc.Sessions {
+		session, err := cf.GetSSOSession(s.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%w (listed in %s)", err, wc.Path())
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func nameData(session *awsconfig.SSOSession, account ssoAccount, role string) waspconfig.NameData {
+	return waspconfig.NameData{
+		Session:      session.Name,
+		SSORegion:    session.Region,
+		AccountID:    account.ID,
+		AccountName:  account.Name,
+		AccountEmail: account.Email,
+		RoleName:     role,
+	}
+}
+
+// profileTargets looks up where existing profiles sign in, so ResolveNames
+// can tell which profiles belong to which account role
+func profileTargets(cf *awsconfig.ConfigFile) func(name string) (waspconfig.Target, bool) {
+	return func(name string) (waspconfig.Target, bool) {
+		p, err := cf.GetProfile(name)
+		if err != nil {
+			return waspconfig.Target{}, false
+		}
+		return waspconfig.Target{Session: p.SSOSession, AccountID: p.AccountID, RoleName: p.RoleName}, true
+	}
+}
+
+// printCollisions reports profiles renamed because their names collided
+func printCollisions(collisions []waspconfig.Collision) {
+	if len(collisions) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Renamed profiles whose names collided:")
+	for _, c := range collisions {
+		fmt.Fprintln(os.Stderr, " ", c)
+	}
+}
+
+// generateProfile creates or updates the profile for an account role,
+// carrying the configured defaults and rules. Keys an earlier sync wrote
+// that no rule sets any more are removed. The profile moves into the file
+// wasp writes to if it's in another one.
+func generateProfile(cf *awsconfig.ConfigFile, wc *waspconfig.Config, name string, data waspconfig.NameData) *awsconfig.Profile {
+	profile := cf.Profile(name)
+	cf.MoveProfile(name)
+	profile.Managed = true
+	profile.Generated = true
+	profile.SSOSession = data.Session
+	profile.AccountID = data.AccountID
+	profile.AccountName = data.AccountName
+	profile.AccountEmail = data.AccountEmail
+	profile.RoleName = data.RoleName
+	profile.RoleARN, profile.SourceProfile, profile.CredentialSource = "", "", ""
+	profile.Settings = make(map[string]string)
+	for _, setting := range wc.Settings(data) {
+		profile.Settings[setting.Key] = setting.Value
+	}
+	return profile
+}
+
+// generateChainedProfile creates or updates a profile that assumes a role
+// from an SSO profile, dropping the SSO keys of a profile it replaces
+func generateChainedProfile(cf *awsconfig.ConfigFile, ch waspconfig.ChainedProfile) *awsconfig.Profile {
+	profile := cf.Profile(ch.Name)
+	cf.MoveProfile(ch.Name)
+	profile.Managed = true
+	profile.Generated = true
+	profile.RoleARN = ch.RoleARN
+	profile.SourceProfile = ch.SourceProfile
+	profile.SSOSession, profile.AccountID, profile.RoleName, profile.CredentialSource = "", "", "", ""
+	profile.AccountName, profile.AccountEmail = "", ""
+	// Only a role in the same account is in the account the names describe
+	if account, _, _ := awsconfig.ParseRoleARN(ch.RoleARN); account == ch.Data.AccountID {
+		profile.AccountName = ch.Data.AccountName
+		profile.AccountEmail = ch.Data.AccountEmail
+	}
+	profile.Settings = make(map[string]string)
+	for _, setting := range ch.Settings {
+		profile.Settings[setting.Key] = setting.Value
+	}
+	return profile
+}
+
+// printChanges shows the sections sync or import would add (+) or change
+// (~), noting where each setting came from and which keys go (-)
+func printChanges(w io.Writer, changes []awsconfig.Change, sources map[string]map[string]string) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, change := range changes {
+		marker := "~"
+		if change.New {
+			marker = "+"
+		}
+		fmt.Fprintf(w, "%s [%s %s]", marker, change.Type, change.Name)
+		if change.MovedFrom != "" {
+			fmt.Fprintf(w, " (moved from %s)", change.MovedFrom)
+		}
+		fmt.Fprintln(w)
+		for _, key := range change.Keys {
+			if key.Removed {
+				fmt.Fprintf(w, "  - %s = %s\t\n", key.Key, key.Old)
+				continue
+			}
+			value := key.New
+			if key.Old != "" {
+				value = fmt.Sprintf("%s → %s", key.Old, key.New)
+			}
+			source := sources[change.Name][key.Key]
+			if source == "" && change.New && change.Type == "profile" && !strings.HasPrefix(key.Key, "sso_") && !strings.HasPrefix(key.Key, "wasp_") {
+				source = "[default]"
+			}
+			if source != "" {
+				source = "# " + source
+			}
+			fmt.Fprintf(w, "    %s = %s\t%s\n", key.Key, value, source)
+		}
+	}
 }
 
 type stringMsg string
@@ -172,7 +371,7 @@ type syncModel struct {
 }
 
 func (m syncModel) LoadConfigFileCmd() tea.Msg {
-	cf, err := awsconfig.NewFromConfig(config.DefaultSharedConfigFilename())
+	cf, err := loadConfigFile()
 	if err != nil {
 		return errorMsg{msg: err.Error()}
 	}
@@ -0,0 +1,312 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+	"github.com/spf13/cobra"
+)
+
+// tokenCmd represents the token command
+var tokenCmd = &cobra.Command{
+	Use:     "token",
+	Aliases: []string{"tokens"},
+	Short:   "Manage cached SSO tokens",
+	Long: `Token inspects and manages the SSO access tokens cached in ~/.aws/sso/cache.
+Cache files are named by a hash of the SSO session, so wasp maps each file
+back to the session in your AWS config file it belongs to. Files that don't
+belong to any configured session are reported as orphaned.`,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List cached SSO tokens",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		tokens, _, err := cachedTokens(cf)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SESSION\tSTART URL\tREGION\tEXPIRES\tSTATUS")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.displayName(), t.Token.StartURL, t.Token.Region, formatExpiry(t.Token.ExpiresAt, now), t.status(now))
+		}
+		return w.Flush()
+	},
+}
+
+var tokenShowCmd = &cobra.Command{
+	Use:   "show [session]",
+	Short: "Show the cached token for an SSO session",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		session, err := resolveSSOSession(cf, args)
+		if err != nil {
+			return err
+		}
+		path, err := ssocache.Path(session.Name)
+		if err != nil {
+			return err
+		}
+		token, err := ssocache.Load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no cached token for SSO session %s", session.Name)
+		} else if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		refreshable := "no"
+		if token.CanRefresh(now) {
+			refreshable = "yes"
+			if !token.RegistrationExpiresAt.IsZero() {
+				refreshable += fmt.Sprintf(" (client registration expires %s)", formatExpiry(token.RegistrationExpiresAt, now))
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Session:\t%s\n", session.Name)
+		fmt.Fprintf(w, "Cache file:\t%s\n", path)
+		fmt.Fprintf(w, "Start URL:\t%s\n", token.StartURL)
+		fmt.Fprintf(w, "Region:\t%s\n", token.Region)
+		fmt.Fprintf(w, "Expires:\t%s\n", formatExpiry(token.ExpiresAt, now))
+		fmt.Fprintf(w, "Status:\t%s\n", cachedToken{Entry: ssocache.Entry{Path: path, Token: token}, Session: session.Name}.status(now))
+		fmt.Fprintf(w, "Refreshable:\t%s\n", refreshable)
+		return w.Flush()
+	},
+}
+
+var tokenClearCmd = &cobra.Command{
+	Use:   "clear [session]",
+	Short: "Delete cached SSO tokens",
+	Long: `Clear deletes the cached token for an SSO session. Use --orphaned to delete
+tokens that don't belong to any session in your AWS config file, or --all to
+delete every cached token.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orphaned, _ := cmd.Flags().GetBool("orphaned")
+		all, _ := cmd.Flags().GetBool("all")
+		yes, _ := cmd.Flags().GetBool("yes")
+		if len(args) == 0 && !orphaned && !all {
+			return errors.New("specify an SSO session, --orphaned or --all")
+		}
+
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		tokens, dir, err := cachedTokens(cf)
+		if err != nil {
+			return err
+		}
+
+		var remove []cachedToken
+		for _, t := range tokens {
+			switch {
+			case all,
+				orphaned && t.Session == "",
+				len(args) == 1 && t.Session == args[0]:
+				remove = append(remove, t)
+			}
+		}
+		if len(remove) == 0 {
+			fmt.Fprintln(os.Stderr, "No matching cached tokens.")
+			return nil
+		}
+
+		for _, t := range remove {
+			fmt.Fprintf(os.Stderr, "  %s\t%s\n", t.displayName(), t.Path)
+		}
+		if !yes && !confirm(fmt.Sprintf("Delete %d cached token(s)?", len(remove))) {
+			return errors.New("aborted")
+		}
+		for _, t := range remove {
+			if err := ssocache.Remove(dir, t.Path); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Deleted %d cached token(s).\n", len(remove))
+		return nil
+	},
+}
+
+var tokenRefreshCmd = &cobra.Command{
+	Use:   "refresh [session]",
+	Short: "Refresh the cached token for an SSO session",
+	Long: `Refresh exchanges the cached refresh token for a new access token without
+opening a browser, even if the current access token is still valid.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cf, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		session, err := resolveSSOSession(cf, args)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		clients, err := newSSOClients(ctx, session)
+		if err != nil {
+			return err
+		}
+		token, err := ssocache.Load(clients.tokenPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no cached token for SSO session %s", session.Name)
+		} else if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := token.Refresh(ctx, clients.oidc, now); err != nil {
+			return err
+		}
+		if err := token.Save(clients.tokenPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Refreshed %s SSO session, expires %s.\n", session.Name, formatExpiry(token.ExpiresAt, now))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenListCmd, tokenShowCmd, tokenClearCmd, tokenRefreshCmd)
+
+	tokenClearCmd.Flags().Bool("orphaned", false, "delete tokens for sessions no longer in the AWS config file")
+	tokenClearCmd.Flags().Bool("all", false, "delete every cached token")
+	tokenClearCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+}
+
+// cachedToken is a cache entry and the SSO session (or legacy profile) it
+// belongs to. Session is empty for orphaned tokens.
+type cachedToken struct {
+	ssocache.Entry
+	Session string
+	Legacy  bool
+}
+
+func (t cachedToken) displayName() string {
+	switch {
+	case t.Session == "":
+		return "-"
+	case t.Legacy:
+		return t.Session + " (legacy)"
+	}
+	return t.Session
+}
+
+func (t cachedToken) status(now time.Time) string {
+	switch {
+	case t.Session == "":
+		return "orphaned"
+	case !t.Token.Expired(now):
+		return "valid"
+	case t.Token.CanRefresh(now):
+		return "expired (refreshable)"
+	}
+	return "expired"
+}
+
+// cachedTokens lists the token cache and matches each file with the SSO
+// session it belongs to. Tokens are keyed by session name, or by start URL
+// for legacy profiles that configure SSO without a session; those tokens
+// belong to the profile.
+func cachedTokens(cf *awsconfig.ConfigFile) ([]cachedToken, string, error) {
+	dir, err := ssocache.Dir()
+	if err != nil {
+		return nil, "", err
+	}
+	entries, err := ssocache.List(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	owners := make(map[string]cachedToken)
+	for key, owner := range cf.TokenOwners() {
+		if path, err := ssocache.Path(key); err == nil {
+			owners[path] = cachedToken{Session: owner.Name, Legacy: owner.Legacy}
+		}
+	}
+
+	var tokens []cachedToken
+	for _, entry := range entries {
+		t := owners[entry.Path]
+		t.Entry = entry
+		tokens = append(tokens, t)
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Session != "" && (tokens[j].Session == "" || tokens[i].Session < tokens[j].Session)
+	})
+	return tokens, dir, nil
+}
+
+// resolveSSOSession returns the session named in args, or the only session
+// in the config file if none was given.
+func resolveSSOSession(cf *awsconfig.ConfigFile, args []string) (*awsconfig.SSOSession, error) {
+	if len(args) == 1 {
+		return cf.GetSSOSession(args[0])
+	}
+	sessions := cf.SSOSessions.List()
+	switch len(sessions) {
+	case 0:
+		return nil, errors.New("no SSO sessions found in AWS config file")
+	case 1:
+		return sessions[0], nil
+	}
+	var names []string
+	for _, session := range sessions {
+		names = append(names, session.Name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("multiple SSO sessions configured, specify one of: %s", strings.Join(names, ", "))
+}
+
+// formatExpiry renders an expiry time with how long is left until it
+func formatExpiry(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	left := t.Sub(now)
+	if left < 0 {
+		return fmt.Sprintf("%s (%s ago)", t.Local().Format(time.DateTime), formatDuration(-left))
+	}
+	return fmt.Sprintf("%s (in %s)", t.Local().Format(time.DateTime), formatDuration(left))
+}
+
+// formatDuration renders a duration at minute precision, e.g. 2d3h or 45m
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days, hours, minutes := int(d.Hours())/24, int(d.Hours())%24, int(d.Minutes())%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
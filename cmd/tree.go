@@ -0,0 +1,266 @@
+/*
+Copyright © 2024 buzzsurfr
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"charm.land/bubbles/v2/help"
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/table"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// treeNode is a group (SSO session or account) or a leaf (role) in the
+// tree view. Leaves carry the table row they resolve to and the style of
+// their label.
+type treeNode struct {
+	label    string
+	style    lipgloss.Style
+	row      table.Row
+	parent   *treeNode
+	children []*treeNode
+	expanded bool
+	leaves   int
+}
+
+func (n *treeNode) isLeaf() bool {
+	return n.row != nil
+}
+
+// treeLeaf is an item to place in the tree, e.g. a profile under
+// ["corp", "Acme Production (111111111111)"]
+type treeLeaf struct {
+	path  []string
+	label string
+	style lipgloss.Style
+	row   table.Row
+}
+
+// buildTree groups leaves by their paths. Groups and leaves are sorted by
+// label and every group is collapsed.
+func buildTree(leaves []treeLeaf) []*treeNode {
+	root := &treeNode{}
+	for _, leaf := range leaves {
+		parent := root
+		for _, label := range leaf.path {
+			var group *treeNode
+			for _, child := range parent.children {
+				if !child.isLeaf() && child.label == label {
+					group = child
+					break
+				}
+			}
+			if group == nil {
+				group = &treeNode{label: label, parent: parent}
+				parent.children = append(parent.children, group)
+			}
+			parent = group
+		}
+		parent.children = append(parent.children, &treeNode{label: leaf.label, style: leaf.style, row: leaf.row, parent: parent})
+	}
+	sortTree(root)
+	for _, n := range root.children {
+		n.parent = nil
+	}
+	return root.children
+}
+
+func sortTree(n *treeNode) int {
+	if n.isLeaf() {
+		return 1
+	}
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return n.children[i].label < n.children[j].label
+	})
+	n.leaves = 0
+	for _, child := range n.children {
+		n.leaves += sortTree(child)
+	}
+	return n.leaves
+}
+
+// treeLine is a visible node and how deep it is nested
+type treeLine struct {
+	node  *treeNode
+	depth int
+}
+
+type treeModel struct {
+	title    string
+	roots    []*treeNode
+	lines    []treeLine
+	cursor   int
+	offset   int
+	height   int
+	selected table.Row
+	quitting bool
+	help     help.Model
+	keyMap   keyMap
+}
+
+func newTreeModel(title string, roots []*treeNode, height int) treeModel {
+	m := treeModel{
+		title:  title,
+		roots:  roots,
+		height: height,
+		help:   help.New(),
+		keyMap: newKeyMap(),
+	}
+	// A single top level group is expanded so there's something to pick from
+	if len(roots) == 1 {
+		roots[0].expanded = true
+	}
+	m.flatten()
+	return m
+}
+
+// flatten rebuilds the visible lines from the expanded nodes
+func (m *treeModel) flatten() {
+	m.lines = m.lines[:0]
+	var walk func(nodes []*treeNode, depth int)
+	walk = func(nodes []*treeNode, depth int) {
+		for _, n := range nodes {
+			m.lines = append(m.lines, treeLine{n, depth})
+			if n.expanded {
+				walk(n.children, depth+1)
+			}
+		}
+	}
+	walk(m.roots, 0)
+	m.offset = min(m.offset, max(0, len(m.lines)-m.height))
+	m.moveTo(m.cursor)
+}
+
+func (m *treeModel) moveTo(cursor int) {
+	m.cursor = max(0, min(cursor, len(m.lines)-1))
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+}
+
+func (m *treeModel) current() *treeNode {
+	if len(m.lines) == 0 {
+		return nil
+	}
+	return m.lines[m.cursor].node
+}
+
+func (m *treeModel) selectNode(n *treeNode) {
+	for i, line := range m.lines {
+		if line.node == n {
+			m.moveTo(i)
+			return
+		}
+	}
+}
+
+func (m treeModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m treeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyPressMsg)
+	if !ok {
+		return m, nil
+	}
+	n := m.current()
+
+	switch {
+	case keyMsg.String() == "ctrl+c", keyMsg.String() == "q", keyMsg.String() == "esc":
+		m.quitting = true
+		return m, tea.Quit
+	case keyMsg.String() == "enter":
+		if n == nil {
+			return m, nil
+		}
+		if n.isLeaf() {
+			m.selected = n.row
+			m.quitting = true
+			return m, tea.Quit
+		}
+		n.expanded = !n.expanded
+		m.flatten()
+	case key.Matches(keyMsg, m.keyMap.Expand):
+		if n != nil && !n.isLeaf() {
+			if !n.expanded {
+				n.expanded = true
+				m.flatten()
+			} else {
+				m.moveTo(m.cursor + 1)
+			}
+		}
+	case key.Matches(keyMsg, m.keyMap.Collapse):
+		if n != nil && !n.isLeaf() && n.expanded {
+			n.expanded = false
+			m.flatten()
+		} else if n != nil && n.parent != nil {
+			m.selectNode(n.parent)
+		}
+	case key.Matches(keyMsg, m.keyMap.LineUp):
+		m.moveTo(m.cursor - 1)
+	case key.Matches(keyMsg, m.keyMap.LineDown):
+		m.moveTo(m.cursor + 1)
+	case key.Matches(keyMsg, m.keyMap.PageUp):
+		m.moveTo(m.cursor - m.height)
+	case key.Matches(keyMsg, m.keyMap.PageDown):
+		m.moveTo(m.cursor + m.height)
+	case key.Matches(keyMsg, m.keyMap.HalfPageUp):
+		m.moveTo(m.cursor - m.height/2)
+	case key.Matches(keyMsg, m.keyMap.HalfPageDown):
+		m.moveTo(m.cursor + m.height/2)
+	case key.Matches(keyMsg, m.keyMap.GotoTop):
+		m.moveTo(0)
+	case key.Matches(keyMsg, m.keyMap.GotoBottom):
+		m.moveTo(len(m.lines) - 1)
+	}
+	return m, nil
+}
+
+func (m treeModel) View() tea.View {
+	if m.quitting {
+		return tea.NewView("")
+	}
+	groupStyle := lipgloss.NewStyle().Bold(true)
+	countStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var b strings.Builder
+	end := min(m.offset+m.height, len(m.lines))
+	for i := m.offset; i < end; i++ {
+		line := m.lines[i]
+		indent := strings.Repeat("  ", line.depth)
+		label, count := line.node.label, ""
+		marker := "  "
+		if !line.node.isLeaf() {
+			marker = "▸ "
+			if line.node.expanded {
+				marker = "▾ "
+			}
+			count = fmt.Sprintf(" · %d", line.node.leaves)
+		}
+		var text string
+		if i == m.cursor {
+			text = tableStyle.Selected.Render(indent + marker + label + count)
+		} else if line.node.isLeaf() {
+			text = indent + marker + line.node.style.Render(label)
+		} else {
+			text = indent + marker + groupStyle.Render(label) + countStyle.Render(count)
+		}
+		b.WriteString(text + "\n")
+	}
+	return tea.NewView("\n" + m.title + "\n" + baseStyle.Render(b.String()) + "\n" + m.help.View(m.keyMap))
+}
+
+// accountLabel names an account group by its name and ID
+func accountLabel(name, id string) string {
+	if name == "" {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", name, id)
+}
@@ -0,0 +1,80 @@
+package accountcache
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+)
+
+const fileName = "accounts.json"
+
+// Account is the metadata AWS SSO returns for an account
+type Account struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// Cache holds the accounts last discovered for an SSO session, so their
+// names can be shown without calling AWS.
+type Cache struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Accounts  []Account `json:"accounts"`
+}
+
+func path(session string) (string, error) {
+	dir, err := waspdir.SessionCacheDir(session)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads the cache for an SSO session. A missing cache is empty.
+func Load(session string) (*Cache, error) {
+	p, err := path(session)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Cache{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var c Cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save writes the cache for an SSO session
+func (c *Cache) Save(session string) error {
+	p, err := path(session)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o600)
+}
+
+// Lookup finds an account by ID
+func (c *Cache) Lookup(id string) (Account, bool) {
+	for _, a := range c.Accounts {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Account{}, false
+}
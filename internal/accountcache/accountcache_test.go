@@ -0,0 +1,38 @@
+package accountcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("WASP_HOME", t.TempDir())
+
+	empty, err := Load("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Accounts) != 0 {
+		t.Fatalf("expected a missing cache to be empty, got %v", empty.Accounts)
+	}
+
+	c := &Cache{
+		UpdatedAt: time.Now(),
+		Accounts:  []Account{{ID: "111111111111", Name: "Acme Production", Email: "prod@example.com"}},
+	}
+	if err := c.Save("corp"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, ok := loaded.Lookup("111111111111")
+	if !ok || account.Name != "Acme Production" {
+		t.Errorf("expected to find Acme Production, got %+v", account)
+	}
+	if _, ok := loaded.Lookup("222222222222"); ok {
+		t.Error("expected an unknown account not to be found")
+	}
+}
@@ -0,0 +1,38 @@
+package awsconfig
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Partition returns the ARN partition a region is in: aws-us-gov for
+// GovCloud, aws-cn for China and aws for the rest
+func Partition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	}
+	return "aws"
+}
+
+// RoleARN returns the ARN of a role in an account, in the partition of the
+// given region
+func RoleARN(region, accountID, roleName string) string {
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", Partition(region), accountID, roleName)
+}
+
+// ParseRoleARN splits an IAM role ARN into its account ID and role name
+func ParseRoleARN(arn string) (accountID, roleName string, ok bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "iam" {
+		return "", "", false
+	}
+	rolePath, ok := strings.CutPrefix(parts[5], "role/")
+	if !ok || rolePath == "" || strings.HasSuffix(rolePath, "/") {
+		return "", "", false
+	}
+	return parts[4], path.Base(rolePath), parts[4] != ""
+}
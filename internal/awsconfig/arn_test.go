@@ -0,0 +1,30 @@
+package awsconfig
+
+import "testing"
+
+func TestRoleARN(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{"", "arn:aws:iam::111111111111:role/Admin"},
+		{"eu-west-1", "arn:aws:iam::111111111111:role/Admin"},
+		{"us-gov-west-1", "arn:aws-us-gov:iam::111111111111:role/Admin"},
+		{"cn-north-1", "arn:aws-cn:iam::111111111111:role/Admin"},
+	}
+	for _, tt := range tests {
+		if got := RoleARN(tt.region, "111111111111", "Admin"); got != tt.want {
+			t.Errorf("RoleARN(%q) = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoleARN(t *testing.T) {
+	account, role, ok := ParseRoleARN("arn:aws-us-gov:iam::111111111111:role/path/Admin")
+	if !ok || account != "111111111111" || role != "Admin" {
+		t.Errorf("ParseRoleARN() = %q, %q, %v", account, role, ok)
+	}
+	if _, _, ok := ParseRoleARN("arn:aws:iam::111111111111:user/bob"); ok {
+		t.Error("ParseRoleARN accepted a user ARN")
+	}
+}
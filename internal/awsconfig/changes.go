@@ -0,0 +1,94 @@
+package awsconfig
+
+import (
+	"slices"
+	"sort"
+)
+
+// Change is how Update would change a profile or sso-session section
+type Change struct {
+	// Type is the section type, profile or sso-session
+	Type string
+	Name string
+	// New is set when the section doesn't exist yet
+	New bool
+	// MovedFrom is the file the section is moved out of, if it's moved
+	MovedFrom string
+	Keys      []KeyChange
+}
+
+// KeyChange is a key Update would add, change or remove. Old is empty for
+// added keys.
+type KeyChange struct {
+	Key     string
+	Old     string
+	New     string
+	Removed bool
+}
+
+// Changes returns what Update would write to or remove from sso-session
+// and profile sections, sessions first and each sorted by name. Sections
+// Update would leave alone are left out.
+func (cf *ConfigFile) Changes() []Change {
+	var changes []Change
+	for _, profile := range cf.Profiles.m {
+		change := Change{Type: "profile", Name: profile.Name, MovedFrom: cf.moved[profile.Name]}
+		old := make(map[string]string)
+		var order []string
+		if section, err := cf.source(profile.Source).iniFile.GetSection(profileSectionName(profile.Name)); err == nil {
+			old = section.KeysHash()
+		} else {
+			// New sections start as a copy of the default profile
+			change.New = true
+			if defaultSection := cf.defaultSection(); defaultSection != nil && !profile.NoDefaults {
+				for _, key := range defaultSection.Keys() {
+					change.Keys = append(change.Keys, KeyChange{Key: key.Name(), New: key.Value()})
+					order = append(order, key.Name())
+				}
+			}
+		}
+
+		for _, kv := range profile.keys() {
+			if i := slices.Index(order, kv.Key); i >= 0 {
+				change.Keys[i].New = kv.Value
+				continue
+			}
+			if value, ok := old[kv.Key]; ok && value == kv.Value {
+				continue
+			}
+			change.Keys = append(change.Keys, KeyChange{Key: kv.Key, Old: old[kv.Key], New: kv.Value})
+			order = append(order, kv.Key)
+		}
+		for _, key := range profile.removedKeys(old) {
+			change.Keys = append(change.Keys, KeyChange{Key: key, Old: old[key], Removed: true})
+		}
+		if change.New || change.MovedFrom != "" || len(change.Keys) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	for _, session := range cf.SSOSessions.m {
+		change := Change{Type: "sso-session", Name: session.Name}
+		old := make(map[string]string)
+		if section, err := cf.source(session.Source).iniFile.GetSection("sso-session " + session.Name); err == nil {
+			old = section.KeysHash()
+		} else {
+			change.New = true
+		}
+		for _, kv := range session.keys() {
+			if value, ok := old[kv.Key]; ok && value == kv.Value {
+				continue
+			}
+			change.Keys = append(change.Keys, KeyChange{Key: kv.Key, Old: old[kv.Key], New: kv.Value})
+		}
+		if change.New || len(change.Keys) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Type != changes[j].Type {
+			return changes[i].Type == "sso-session"
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
@@ -0,0 +1,182 @@
+package awsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[default]
+region = us-east-1
+output = json
+
+[profile Acme Production_ReadOnly]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+region = us-east-1
+
+[profile unchanged]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prod := cf.Profile("Acme Production_ReadOnly")
+	prod.Settings = map[string]string{"region": "eu-west-1", "output": "table"}
+	dev := cf.Profile("Acme Dev_ReadOnly")
+	dev.SSOSession = "corp"
+	dev.AccountID = "222222222222"
+	dev.RoleName = "ReadOnly"
+	dev.Settings = map[string]string{"output": "table"}
+
+	want := []Change{
+		{
+			Type: "profile",
+			Name: "Acme Dev_ReadOnly",
+			New:  true,
+			Keys: []KeyChange{
+				{Key: "region", New: "us-east-1"},
+				{Key: "output", New: "table"},
+				{Key: "sso_session", New: "corp"},
+				{Key: "sso_account_id", New: "222222222222"},
+				{Key: "sso_role_name", New: "ReadOnly"},
+			},
+		},
+		{
+			Type: "profile",
+			Name: "Acme Production_ReadOnly",
+			Keys: []KeyChange{
+				{Key: "output", New: "table"},
+				{Key: "region", Old: "us-east-1", New: "eu-west-1"},
+			},
+		},
+	}
+	if got := cf.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %+v, want %+v", got, want)
+	}
+
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cf.Changes(); len(got) != 0 {
+		t.Errorf("Changes() after Update = %+v, want none", got)
+	}
+}
+
+func TestChangesRemovedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[profile prod]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+wasp_managed = true
+wasp_keys = output,region,sso_account_id,sso_role_name,sso_session
+output = json
+region = eu-west-1
+cli_pager = less
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prod := cf.Profile("prod")
+	prod.Generated = true
+	prod.Settings = map[string]string{"region": "eu-west-1"}
+
+	want := []Change{{
+		Type: "profile",
+		Name: "prod",
+		Keys: []KeyChange{
+			{Key: "wasp_keys", Old: "output,region,sso_account_id,sso_role_name,sso_session", New: "region,sso_account_id,sso_role_name,sso_session"},
+			{Key: "output", Old: "json", Removed: true},
+		},
+	}}
+	if got := cf.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %+v, want %+v", got, want)
+	}
+
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+	keys := cf.ProfileKeys("prod")
+	if _, ok := keys["output"]; ok {
+		t.Error("output still set after Update")
+	}
+	// Keys wasp didn't write are left alone
+	if keys["cli_pager"] != "less" {
+		t.Errorf("cli_pager = %q, want less", keys["cli_pager"])
+	}
+	if got := cf.Changes(); len(got) != 0 {
+		t.Errorf("Changes() after Update = %+v, want none", got)
+	}
+}
+
+func TestChangesRemovedCredentialKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[profile ci]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+wasp_managed = true
+region = eu-west-1
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A profile written before wasp_keys that now assumes a role loses the
+	// keys that signed it in with SSO, and keeps the rest
+	ci := cf.Profile("ci")
+	ci.Generated = true
+	ci.SSOSession, ci.AccountID, ci.RoleName = "", "", ""
+	ci.RoleARN = "arn:aws:iam::111111111111:role/CI"
+	ci.CredentialSource = "Ec2InstanceMetadata"
+
+	want := []Change{{
+		Type: "profile",
+		Name: "ci",
+		Keys: []KeyChange{
+			{Key: "role_arn", New: "arn:aws:iam::111111111111:role/CI"},
+			{Key: "credential_source", New: "Ec2InstanceMetadata"},
+			{Key: "wasp_keys", New: "credential_source,role_arn"},
+			{Key: "sso_account_id", Old: "111111111111", Removed: true},
+			{Key: "sso_role_name", Old: "Admin", Removed: true},
+			{Key: "sso_session", Old: "corp", Removed: true},
+		},
+	}}
+	if got := cf.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %+v, want %+v", got, want)
+	}
+
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+	keys := cf.ProfileKeys("ci")
+	for _, key := range []string{"sso_session", "sso_account_id", "sso_role_name"} {
+		if _, ok := keys[key]; ok {
+			t.Errorf("%s still set after Update", key)
+		}
+	}
+	if keys["region"] != "eu-west-1" {
+		t.Errorf("region = %q, want eu-west-1", keys["region"])
+	}
+}
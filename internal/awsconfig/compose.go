@@ -0,0 +1,127 @@
+package awsconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// composedHeader starts every file Compose writes, so it can tell its own
+// files from hand-written ones. The checksum line after it covers the rest
+// of the file, so Compose can tell when someone edited it since.
+const (
+	composedHeader = "# Composed by wasp; edit the files listed below and run wasp compose instead.\n"
+	checksumPrefix = "# Checksum: sha256:"
+)
+
+var (
+	// ErrNotComposed is returned by Compose rather than overwrite a file it
+	// didn't write
+	ErrNotComposed = errors.New("not composed by wasp")
+	// ErrComposedChanged is returned by Compose rather than overwrite a
+	// file that was edited since it was composed, e.g. by aws configure
+	ErrComposedChanged = errors.New("changed since wasp composed it")
+)
+
+// CheckCompose reports whether Compose may write dst: it doesn't exist yet,
+// or it was composed by wasp and hasn't been edited since. Files composed
+// before wasp wrote checksums are taken as unedited.
+func CheckCompose(dst string, sources ...string) error {
+	if slices.Contains(sources, dst) {
+		return fmt.Errorf("%s can't be composed from itself; move it to another file and list that in files.sources", dst)
+	}
+	existing, err := os.ReadFile(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	rest, ok := bytes.CutPrefix(existing, []byte(composedHeader))
+	if !ok {
+		return fmt.Errorf("%s: %w; move its profiles into a source file first", dst, ErrNotComposed)
+	}
+	if !bytes.HasPrefix(rest, []byte(checksumPrefix)) {
+		return nil
+	}
+	line, body, _ := bytes.Cut(rest, []byte("\n"))
+	if string(line[len(checksumPrefix):]) != checksum(body) {
+		return fmt.Errorf("%s: %w; move the changes into a source file first", dst, ErrComposedChanged)
+	}
+	return nil
+}
+
+// Compose concatenates the source config files into dst, the file the AWS
+// CLI and SDKs read. Missing sources are skipped. dst is replaced
+// atomically, and only if CheckCompose allows it.
+func Compose(dst string, sources ...string) error {
+	if err := CheckCompose(dst, sources...); err != nil {
+		return err
+	}
+	return compose(dst, sources)
+}
+
+// ForceCompose composes dst even if it was edited since it was composed.
+// It still won't overwrite a file wasp didn't compose.
+func ForceCompose(dst string, sources ...string) error {
+	if err := CheckCompose(dst, sources...); err != nil && !errors.Is(err, ErrComposedChanged) {
+		return err
+	}
+	return compose(dst, sources)
+}
+
+func compose(dst string, sources []string) error {
+	var body bytes.Buffer
+	for _, source := range sources {
+		data, err := os.ReadFile(source)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintf(&body, "\n# Source: %s\n", source)
+		body.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			body.WriteByte('\n')
+		}
+	}
+	var b bytes.Buffer
+	b.WriteString(composedHeader)
+	fmt.Fprintf(&b, "%s%s\n", checksumPrefix, checksum(body.Bytes()))
+	b.Write(body.Bytes())
+
+	mode := fs.FileMode(0o600)
+	if info, err := os.Stat(dst); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".wasp-config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
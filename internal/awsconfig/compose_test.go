@@ -0,0 +1,86 @@
+package awsconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	dir := t.TempDir()
+	personal := filepath.Join(dir, "personal")
+	managed := filepath.Join(dir, "wasp")
+	dst := filepath.Join(dir, "config")
+	if err := os.WriteFile(personal, []byte("[default]\nregion = us-east-1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(managed, []byte("[profile dev]\nsso_session = corp\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("[default]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A hand-written file isn't overwritten
+	if err := Compose(dst, personal, managed); !errors.Is(err, ErrNotComposed) {
+		t.Fatalf("Compose() = %v, want ErrNotComposed", err)
+	}
+	if err := os.Remove(dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Compose(dst, personal, filepath.Join(dir, "missing"), managed); err != nil {
+		t.Fatal(err)
+	}
+	// Composing again replaces the composed file
+	if err := Compose(dst, personal, managed); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cf.HasProfile("default") || !cf.HasProfile("dev") {
+		t.Errorf("composed profiles = %v", cf.Profiles.Map())
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# Source: "+managed) {
+		t.Errorf("composed file doesn't name its sources:\n%s", data)
+	}
+
+	// Edits made to the composed file, e.g. by aws configure, aren't lost
+	edited := strings.Replace(string(data), "region = us-east-1", "region = eu-west-1", 1)
+	if err := os.WriteFile(dst, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compose(dst, personal, managed); !errors.Is(err, ErrComposedChanged) {
+		t.Fatalf("Compose() = %v, want ErrComposedChanged", err)
+	}
+	if err := ForceCompose(dst, personal, managed); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckCompose(dst, personal, managed); err != nil {
+		t.Errorf("CheckCompose() after ForceCompose = %v", err)
+	}
+
+	// Files composed before checksums are taken as unedited
+	if err := os.WriteFile(dst, []byte(composedHeader+"[default]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckCompose(dst, personal, managed); err != nil {
+		t.Errorf("CheckCompose() without a checksum = %v", err)
+	}
+
+	// Forcing still leaves hand-written files alone
+	if err := os.WriteFile(dst, []byte("[default]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ForceCompose(dst, personal, managed); !errors.Is(err, ErrNotComposed) {
+		t.Errorf("ForceCompose() = %v, want ErrNotComposed", err)
+	}
+}
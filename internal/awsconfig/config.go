@@ -1,21 +1,37 @@
 package awsconfig
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"gopkg.in/ini.v1"
 )
 
-// ConfigFile represents the AWS config file structure
+// ConfigFile represents the AWS config file structure. It can be merged
+// from several files; each section remembers its Source file and new
+// sections are written to the first file given.
 type ConfigFile struct {
 	file        string
-	iniFile     *ini.File
+	sources     []*sourceFile
+	moved       map[string]string
 	Profiles    *Profiles
 	Services    *Services
 	SSOSessions *SSOSessions
 }
 
+// sourceFile is one of the files a ConfigFile was loaded from
+type sourceFile struct {
+	path    string
+	iniFile *ini.File
+	raw     []RawSection
+	dirty   bool
+}
+
 type Section interface {
 	Type() string
 	colWidths() map[string]int
@@ -43,6 +59,67 @@ func (cf ConfigFile) Profile(name string) *Profile {
 	return cf.Profiles.m[name]
 }
 
+// DeleteProfile removes a profile and its section from the config file
+func (cf ConfigFile) DeleteProfile(name string) {
+	profile := cf.Profiles.m[name]
+	if profile == nil {
+		return
+	}
+	delete(cf.Profiles.m, name)
+	src := cf.source(profile.Source)
+	src.iniFile.DeleteSection(profileSectionName(name))
+	src.dirty = true
+}
+
+// MoveProfile moves a profile's section, with every key in it, into the
+// file new sections are written to
+func (cf ConfigFile) MoveProfile(name string) {
+	profile := cf.Profiles.m[name]
+	target := cf.source(cf.file)
+	if profile == nil || profile.Source == target.path {
+		return
+	}
+	src := cf.source(profile.Source)
+	sectionName := profileSectionName(name)
+	if section, err := src.iniFile.GetSection(sectionName); err == nil {
+		moved, _ := target.iniFile.NewSection(sectionName)
+		for _, key := range section.Keys() {
+			moved.Key(key.Name()).SetValue(key.Value())
+		}
+		src.iniFile.DeleteSection(sectionName)
+		src.dirty = true
+		target.dirty = true
+		cf.moved[name] = src.path
+	}
+	profile.Source = target.path
+}
+
+// ProfileKeys returns every key in a profile's section as written, including
+// the ones Profile doesn't have a field for, such as region
+func (cf ConfigFile) ProfileKeys(name string) map[string]string {
+	profile := cf.Profiles.m[name]
+	if profile == nil {
+		return nil
+	}
+	section, err := cf.source(profile.Source).iniFile.GetSection(profileSectionName(name))
+	if err != nil {
+		return nil
+	}
+	return section.KeysHash()
+}
+
+// ProfileSettings returns the keys in a profile's section that Profile has
+// no field for, such as region
+func (cf ConfigFile) ProfileSettings(name string) map[string]string {
+	settings := cf.ProfileKeys(name)
+	for key := range settings {
+		if slices.Contains(credentialKeys, key) || key == "services" || strings.HasPrefix(key, "wasp_") {
+			delete(settings, key)
+		}
+	}
+	return settings
+}
+
 func (cf ConfigFile) GetService(name string) (*Service, error) {
 	service := cf.Services.m[name]
 	if service == nil {
@@ -87,19 +164,28 @@ func (cf ConfigFile) SSOSession(name string) *SSOSession {
 	return cf.SSOSessions.m[name]
 }
 
+// Load reads a config file and merges its sections into cf. Sections that
+// are already loaded from an earlier file are kept.
 func (cf *ConfigFile) Load(source string) error {
-	// Load config file
-	cf.file = source
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
 	configFile, err := ini.LoadSources(
 		ini.LoadOptions{
 			AllowShadows: true,
 		},
-		source,
+		data,
 	)
 	if err != nil {
 		return err
 	}
-	cf.iniFile = configFile
+	return cf.add(&sourceFile{path: source, iniFile: configFile, raw: parseRaw(source, data)})
+}
+
+func (cf *ConfigFile) add(src *sourceFile) error {
+	cf.sources = append(cf.sources, src)
+	source, configFile := src.path, src.iniFile
 
 	// Parse sections into profiles, services, and SSO sessions
 	for _, section := range configFile.Sections() {
@@ -109,58 +195,104 @@ func (cf *ConfigFile) Load(source string) error {
 		case "unused":
 			continue
 		case "profile":
+			if cf.HasProfile(sectionName) {
+				continue
+			}
 			err := cf.Profiles.NewFromSection(sectionName, section)
 			if err != nil {
 				return err
 			}
-		case "service":
+			cf.Profiles.m[sectionName].Source = source
+		case "service", "services":
+			if cf.HasService(sectionName) {
+				continue
+			}
 			err := cf.Services.NewFromSection(sectionName, section)
 			if err != nil {
 				return err
 			}
+			cf.Services.m[sectionName].Source = source
 		case "sso-session":
+			if cf.HasSSOSession(sectionName) {
+				continue
+			}
 			err := cf.SSOSessions.NewFromSection(sectionName, section)
 			if err != nil {
 				return err
 			}
+			cf.SSOSessions.m[sectionName].Source = source
+		}
+	}
+
+	return nil
+}
+
+// source returns the loaded file with the given path, or the file new
+// sections are written to
+func (cf *ConfigFile) source(path string) *sourceFile {
+	for _, src := range cf.sources {
+		if src.path == path {
+			return src
 		}
 	}
+	for _, src := range cf.sources {
+		if src.path == cf.file {
+			return src
+		}
+	}
+	return nil
+}
 
+// defaultSection returns the default profile's section from whichever file
+// has it
+func (cf *ConfigFile) defaultSection() *ini.Section {
+	for _, src := range cf.sources {
+		if section, err := src.iniFile.GetSection("default"); err == nil {
+			return section
+		}
+	}
 	return nil
 }
 
+// Files returns the paths of the files the config was loaded from
+func (cf *ConfigFile) Files() []string {
+	var paths []string
+	for _, src := range cf.sources {
+		paths = append(paths, src.path)
+	}
+	return paths
+}
+
+// Update writes profiles and SSO sessions back to the files they came
+// from, and new ones to the first file. Files without changes are left
+// untouched.
 func (cf *ConfigFile) Update() error {
-	// Merge profiles back into the ini file
+	// Merge profiles back into the ini files
 	for _, profile := range cf.Profiles.m {
+		src := cf.source(profile.Source)
+		profile.Source = src.path
 		var section *ini.Section
-		var section_name string
 		// Duplicate from default profile if it doesn't exist
-		if profile.Name == "default" {
-			section_name = "default"
-		} else {
-			section_name = "profile " + profile.Name
-		}
-		if !cf.iniFile.HasSection(section_name) {
-			defaultSection, err := cf.iniFile.GetSection("default")
-			if err != nil {
-				// No defaults found. This should only happen if the config file is empty of malformed.
-				return err
-			}
-			section, _ = cf.iniFile.NewSection(section_name)
-			for key, value := range defaultSection.KeysHash() {
-				section.Key(key).SetValue(value)
+		section_name := profileSectionName(profile.Name)
+		if !src.iniFile.HasSection(section_name) {
+			section, _ = src.iniFile.NewSection(section_name)
+			src.dirty = true
+			// Files without a default profile get a bare section, as do
+			// profiles that ask for one
+			if defaultSection := cf.defaultSection(); defaultSection != nil && !profile.NoDefaults {
+				for key, value := range defaultSection.KeysHash() {
+					section.Key(key).SetValue(value)
+				}
 			}
 		} else {
-			section, _ = cf.iniFile.GetSection(section_name)
-		}
-		if profile.SSOSession != "" {
-			section.Key("sso_session").SetValue(profile.SSOSession)
-		}
-		if profile.AccountID != "" {
-			section.Key("sso_account_id").SetValue(profile.AccountID)
+			section, _ = src.iniFile.GetSection(section_name)
+			for _, key := range profile.removedKeys(section.KeysHash()) {
+				section.DeleteKey(key)
+				src.dirty = true
+			}
 		}
-		if profile.RoleName != "" {
-			section.Key("sso_role_name").SetValue(profile.RoleName)
+		for _, kv := range profile.keys() {
+			src.dirty = setKey(section, kv.Key, kv.Value) || src.dirty
 		}
 	}
 
@@ -171,25 +303,58 @@ func (cf *ConfigFile) Update() error {
 	// 	section, _ := cf.iniFile.Section("service " + service.Name)
 	// }
 
-	// Merge SSO sessions back into the ini file
+	// Merge SSO sessions back into the ini files
 	for _, session := range cf.SSOSessions.m {
-		section := cf.iniFile.Section("sso-session " + session.Name)
-		section.Key("sso_start_url").SetValue(session.StartURL)
-		section.Key("sso_region").SetValue(session.Region)
+		src := cf.source(session.Source)
+		session.Source = src.path
+		if !src.iniFile.HasSection("sso-session " + session.Name) {
+			src.dirty = true
+		}
+		section := src.iniFile.Section("sso-session " + session.Name)
+		src.dirty = setKey(section, "sso_start_url", session.StartURL) || src.dirty
+		src.dirty = setKey(section, "sso_region", session.Region) || src.dirty
 		// section.Key("sso_account_id").SetValue(session.AccountID)
 		// section.Key("sso_role_name").SetValue(session.RoleName)
-		section.Key("sso_registration_scopes").SetValue(strings.Join(session.RegistrationScopes, ","))
+		if scopes := strings.Join(session.RegistrationScopes, ","); scopes != "" || section.HasKey("sso_registration_scopes") {
+			src.dirty = setKey(section, "sso_registration_scopes", scopes) || src.dirty
+		}
 	}
 
-	// Write the ini file back to disk
-	err := cf.iniFile.SaveTo(cf.file)
-	if err != nil {
-		return err
+	// Write the changed ini files back to disk
+	for _, src := range cf.sources {
+		if !src.dirty {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(src.path), 0o755); err != nil {
+			return err
+		}
+		if err := src.iniFile.SaveTo(src.path); err != nil {
+			return err
+		}
+		src.dirty = false
 	}
 
 	return nil
 }
 
+// setKey sets a key, reporting whether that changed the section
+func setKey(section *ini.Section, key, value string) bool {
+	if section.HasKey(key) && section.Key(key).String() == value {
+		return false
+	}
+	section.Key(key).SetValue(value)
+	return true
+}
+
+// profileSectionName returns the section name for a profile. The default
+// profile is the only one without a "profile" prefix.
+func profileSectionName(name string) string {
+	if name == "default" {
+		return "default"
+	}
+	return "profile " + name
+}
+
 // splitSectionText splits the section name into section type and section name.
 // It takes a section string as input and returns the section type and section name as strings.
 // If the section name is unsectioned, it returns "unused" as the section type and the default section name.
@@ -206,7 +371,7 @@ func splitSectionText(sectionText string) (sectionType, sectionName string) {
 		}
 	} else {
 		sectionType = sectionParts[0]
-		sectionName = strings.Join(sectionParts[1:], ",")
+		sectionName = strings.Join(sectionParts[1:], " ")
 	}
 	return sectionType, sectionName
 }
@@ -215,6 +380,7 @@ func splitSectionText(sectionText string) (sectionType, sectionName string) {
 func NewFromConfig(source string) (*ConfigFile, error) {
 	ret := ConfigFile{
 		file:        source,
+		moved:       make(map[string]string),
 		Profiles:    newProfiles(),
 		Services:    newServices(),
 		SSOSessions: newSSOSessions(),
@@ -227,3 +393,35 @@ func NewFromConfig(source string) (*ConfigFile, error) {
 
 	return &ret, nil
 }
+
+// NewFromFiles loads and merges several config files. New sections are
+// written to target, which doesn't have to exist yet. The other files are
+// read first, in order, and a section defined in more than one file comes
+// from the first of them.
+func NewFromFiles(target string, sources ...string) (*ConfigFile, error) {
+	ret := ConfigFile{
+		file:        target,
+		moved:       make(map[string]string),
+		Profiles:    newProfiles(),
+		Services:    newServices(),
+		SSOSessions: newSSOSessions(),
+	}
+
+	for _, source := range sources {
+		if source == target {
+			continue
+		}
+		if err := ret.Load(source); err != nil {
+			return nil, err
+		}
+	}
+	err := ret.Load(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = ret.add(&sourceFile{path: target, iniFile: ini.Empty(ini.LoadOptions{AllowShadows: true})})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
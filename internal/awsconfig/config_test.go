@@ -1,6 +1,9 @@
 package awsconfig
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"gopkg.in/ini.v1"
@@ -18,15 +21,21 @@ func TestSplitSectionText(t *testing.T) {
 			expectedName: "sectionName",
 		},
 		{
+			// A section without a type is a profile, like [default]
 			section:      "unsectioned",
-			expectedType: "unused",
-			expectedName: "default",
+			expectedType: "profile",
+			expectedName: "unsectioned",
 		},
 		{
 			section:      ini.DefaultSection,
 			expectedType: "unused",
 			expectedName: ini.DefaultSection,
 		},
+		{
+			section:      "profile Acme Production_AdministratorAccess",
+			expectedType: "profile",
+			expectedName: "Acme Production_AdministratorAccess",
+		},
 		// Add more test cases here
 	}
 
@@ -40,3 +49,198 @@ func TestSplitSectionText(t *testing.T) {
 		}
 	}
 }
+
+func TestAccountNameRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[default]
+region = us-east-1
+
+[profile Acme Production_AdministratorAccess]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = AdministratorAccess
+wasp_account_name = Acme Production
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := cf.GetProfile("Acme Production_AdministratorAccess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.AccountName != "Acme Production" {
+		t.Errorf("Expected account name Acme Production, but got %q", profile.AccountName)
+	}
+
+	dev := cf.Profile("Acme Development_ReadOnly")
+	dev.SSOSession = "corp"
+	dev.AccountID = "222222222222"
+	dev.RoleName = "ReadOnly"
+	dev.AccountName = "Acme Development"
+	dev.AccountEmail = "dev@example.com"
+	dev.Managed = true
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err = reloaded.GetProfile("Acme Development_ReadOnly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.AccountName != "Acme Development" || dev.AccountEmail != "dev@example.com" || !dev.Managed {
+		t.Errorf("Expected account metadata to be persisted, but got %+v", dev)
+	}
+	if prod, _ := reloaded.GetProfile("Acme Production_AdministratorAccess"); prod.Managed {
+		t.Errorf("Expected hand-written profile to stay unmanaged")
+	}
+}
+
+func TestNewFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	personal := filepath.Join(dir, "personal")
+	managed := filepath.Join(dir, "config.d", "wasp")
+	handWritten := `[default]
+region = us-east-1
+
+[sso-session corp]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+
+[profile Acme Production_ReadOnly]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+`
+	if err := os.WriteFile(personal, []byte(handWritten), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := NewFromFiles(managed, personal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cf.Profile("Acme Production_ReadOnly").Source; got != personal {
+		t.Errorf("Source = %q, want %q", got, personal)
+	}
+	if got := cf.SSOSession("corp").Source; got != personal {
+		t.Errorf("session Source = %q, want %q", got, personal)
+	}
+
+	dev := cf.Profile("Acme Dev_Admin")
+	dev.SSOSession = "corp"
+	dev.AccountID = "222222222222"
+	dev.RoleName = "Admin"
+	dev.Managed = true
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The hand-written file is left byte for byte as it was
+	data, err := os.ReadFile(personal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != handWritten {
+		t.Errorf("%s was rewritten:\n%s", personal, data)
+	}
+
+	cf, err = NewFromFiles(managed, personal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := cf.GetProfile("Acme Dev_Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != managed || !p.Managed || p.AccountID != "222222222222" {
+		t.Errorf("reloaded profile = %+v", p)
+	}
+
+	// Adopting moves a hand-written profile into the managed file
+	cf.MoveProfile("Acme Production_ReadOnly")
+	if changes := cf.Changes(); len(changes) != 1 || changes[0].MovedFrom != personal || len(changes[0].Keys) != 0 {
+		t.Errorf("Changes() = %+v, want a move from %s", changes, personal)
+	}
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+	cf, err = NewFromFiles(managed, personal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cf.Profile("Acme Production_ReadOnly").Source; got != managed {
+		t.Errorf("moved Source = %q, want %q", got, managed)
+	}
+}
+
+func TestTokenOwners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+
+[profile dev]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile old]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 222222222222
+sso_role_name = Admin
+
+[profile same-url]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 333333333333
+sso_role_name = Admin
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]TokenOwner{
+		"corp":                             {Name: "corp"},
+		"https://corp.awsapps.com/start":   {Name: "corp", Legacy: true},
+		"https://legacy.awsapps.com/start": {Name: "old", Legacy: true},
+	}
+	if got := cf.TokenOwners(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TokenOwners() = %+v, want %+v", got, want)
+	}
+}
+
+func TestProfileSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[profile ci]
+role_arn = arn:aws:iam::111111111111:role/CI
+credential_source = Ec2InstanceMetadata
+wasp_managed = true
+region = eu-west-1
+output = json
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"region": "eu-west-1", "output": "json"}
+	if got := cf.ProfileSettings("ci"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ProfileSettings() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package awsconfig
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// CycleError is returned for profiles whose source_profile chain loops
+type CycleError struct {
+	Profiles []string
+}
+
+func (e *CycleError) Error() string {
+	return "source_profile cycle: " + strings.Join(slices.Concat(e.Profiles, e.Profiles[:1]), " → ")
+}
+
+// Chain returns the profiles a profile assumes roles through, starting with
+// the profile itself and ending with the one that has credentials of its
+// own. A profile that names itself as source_profile ends the chain, as it
+// does for the AWS CLI.
+func (cf ConfigFile) Chain(name string) ([]*Profile, error) {
+	var chain []*Profile
+	seen := make(map[string]int)
+	for {
+		profile, err := cf.GetProfile(name)
+		if err != nil {
+			if len(chain) > 0 {
+				return chain, fmt.Errorf("profile %s: source_profile %s not found", chain[len(chain)-1].Name, name)
+			}
+			return nil, err
+		}
+		if i, ok := seen[name]; ok {
+			var names []string
+			for _, p := range chain[i:] {
+				names = append(names, p.Name)
+			}
+			return chain, &CycleError{names}
+		}
+		seen[name] = len(chain)
+		chain = append(chain, profile)
+		if profile.SourceProfile == "" || profile.SourceProfile == name {
+			return chain, nil
+		}
+		name = profile.SourceProfile
+	}
+}
+
+// Cycles returns every source_profile cycle, each starting with its
+// alphabetically first profile
+func (cf ConfigFile) Cycles() [][]string {
+	var cycles [][]string
+	seen := make(map[string]bool)
+	for _, name := range slices.Sorted(maps.Keys(cf.Profiles.m)) {
+		_, err := cf.Chain(name)
+		cycle, ok := err.(*CycleError)
+		if !ok {
+			continue
+		}
+		first := slices.Index(cycle.Profiles, slices.Min(cycle.Profiles))
+		names := append(slices.Clone(cycle.Profiles[first:]), cycle.Profiles[:first]...)
+		if key := strings.Join(names, "\x00"); !seen[key] {
+			seen[key] = true
+			cycles = append(cycles, names)
+		}
+	}
+	return cycles
+}
+
+// Node types in the reference graph besides profile and sso-session
+const (
+	ServicesNode         = "services"
+	CredentialSourceNode = "credential_source"
+)
+
+// credentialSources are the credential_source values the AWS CLI knows
+var credentialSources = []string{"Environment", "Ec2InstanceMetadata", "EcsContainer"}
+
+// Node is a section, or a credential source, in the reference graph
+type Node struct {
+	// Type is profile, sso-session, services or credential_source
+	Type string
+	Name string
+	// Missing is set for references to sections that don't exist and for
+	// credential sources the AWS CLI doesn't know
+	Missing bool
+}
+
+func (n Node) String() string {
+	return n.Type + " " + n.Name
+}
+
+// Edge is a reference from a profile, named after the key that makes it
+type Edge struct {
+	From Node
+	To   Node
+	Key  string
+	// Cycle is set for source_profile references in a cycle
+	Cycle bool
+}
+
+// Graph is how the profiles in a config refer to each other, SSO sessions,
+// services sections and credential sources
+type Graph struct {
+	Nodes  []Node
+	Edges  []Edge
+	Cycles [][]string
+}
+
+// Graph builds the reference graph. Nodes are sorted by type and name, and
+// edges by the node they start from.
+func (cf ConfigFile) Graph() *Graph {
+	g := &Graph{Cycles: cf.Cycles()}
+	inCycle := make(map[[2]string]bool)
+	for _, cycle := range g.Cycles {
+		for i, name := range cycle {
+			inCycle[[2]string{name, cycle[(i+1)%len(cycle)]}] = true
+		}
+	}
+
+	nodes := make(map[Node]bool)
+	for _, p := range cf.Profiles.m {
+		nodes[Node{Type: "profile", Name: p.Name}] = true
+	}
+	for _, s := range cf.SSOSessions.m {
+		nodes[Node{Type: "sso-session", Name: s.Name}] = true
+	}
+	for _, s := range cf.Services.m {
+		nodes[Node{Type: ServicesNode, Name: s.Name}] = true
+	}
+
+	for _, p := range cf.Profiles.m {
+		from := Node{Type: "profile", Name: p.Name}
+		for _, ref := range []struct {
+			key, nodeType, name string
+			exists              bool
+		}{
+			{"source_profile", "profile", p.SourceProfile, cf.HasProfile(p.SourceProfile)},
+			{"sso_session", "sso-session", p.SSOSession, cf.HasSSOSession(p.SSOSession)},
+			{"services", ServicesNode, p.Services, cf.HasService(p.Services)},
+			{"credential_source", CredentialSourceNode, p.CredentialSource, slices.Contains(credentialSources, p.CredentialSource)},
+		} {
+			if ref.name == "" {
+				continue
+			}
+			to := Node{Type: ref.nodeType, Name: ref.name, Missing: !ref.exists}
+			nodes[to] = true
+			g.Edges = append(g.Edges, Edge{From: from, To: to, Key: ref.key, Cycle: inCycle[[2]string{p.Name, ref.name}] && ref.key == "source_profile"})
+		}
+	}
+
+	for n := range nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	slices.SortFunc(g.Nodes, compareNodes)
+	slices.SortFunc(g.Edges, func(a, b Edge) int {
+		if c := compareNodes(a.From, b.From); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return g
+}
+
+func compareNodes(a, b Node) int {
+	if c := strings.Compare(a.Type, b.Type); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// Reachable returns the part of the graph reachable from the given nodes
+// by following references
+func (g *Graph) Reachable(from ...Node) *Graph {
+	keep := make(map[Node]bool)
+	var visit func(n Node)
+	visit = func(n Node) {
+		if keep[n] {
+			return
+		}
+		keep[n] = true
+		for _, e := range g.Edges {
+			if e.From == n {
+				visit(e.To)
+			}
+		}
+	}
+	for _, n := range from {
+		visit(n)
+	}
+
+	sub := &Graph{}
+	for _, n := range g.Nodes {
+		if keep[n] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if keep[e.From] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	for _, cycle := range g.Cycles {
+		if keep[Node{Type: "profile", Name: cycle[0]}] {
+			sub.Cycles = append(sub.Cycles, cycle)
+		}
+	}
+	return sub
+}
@@ -0,0 +1,173 @@
+package awsconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[profile base]
+aws_access_key_id = AKIA
+source_profile = base
+
+[profile admin]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = base
+
+[profile deploy]
+role_arn = arn:aws:iam::222222222222:role/Deploy
+source_profile = admin
+
+[profile x]
+source_profile = y
+
+[profile y]
+source_profile = z
+
+[profile z]
+source_profile = x
+
+[profile dangling]
+source_profile = missing
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := cf.Chain("deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range chain {
+		names = append(names, p.Name)
+	}
+	if want := []string{"deploy", "admin", "base"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Chain(deploy) = %v, want %v", names, want)
+	}
+	if chain[0].RoleARN != "arn:aws:iam::222222222222:role/Deploy" {
+		t.Errorf("RoleARN = %q", chain[0].RoleARN)
+	}
+
+	var cycle *CycleError
+	if _, err := cf.Chain("y"); !errors.As(err, &cycle) || !reflect.DeepEqual(cycle.Profiles, []string{"y", "z", "x"}) {
+		t.Errorf("Chain(y) error = %v", err)
+	}
+	if _, err := cf.Chain("dangling"); err == nil {
+		t.Error("Chain(dangling) succeeded")
+	}
+
+	if got, want := cf.Cycles(), [][]string{{"x", "y", "z"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Cycles() = %v, want %v", got, want)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`[sso-session corp]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+
+[profile dev]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+services = dev-endpoints
+
+[profile dev-org]
+role_arn = arn:aws:iam::111111111111:role/OrganizationAccountAccessRole
+source_profile = dev
+
+[profile ci]
+role_arn = arn:aws:iam::111111111111:role/CI
+credential_source = Ec2Instance
+
+[profile lost]
+sso_session = gone
+
+[profile x]
+source_profile = y
+
+[profile y]
+source_profile = x
+
+[services dev-endpoints]
+s3 =
+  endpoint_url = http://localhost:4566
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := cf.Graph()
+
+	var b strings.Builder
+	if err := g.WriteTree(&b); err != nil {
+		t.Fatal(err)
+	}
+	wantTree := `credential_source Ec2Instance ✗ unknown
+└── ci
+sso-session corp
+└── dev [services dev-endpoints]
+    └── dev-org
+sso-session gone ✗ not defined
+└── lost
+↻ source_profile cycle: x → y → x
+├── x
+└── y
+`
+	if b.String() != wantTree {
+		t.Errorf("WriteTree() =\n%s\nwant\n%s", b.String(), wantTree)
+	}
+
+	b.Reset()
+	if err := g.Reachable(Node{Type: "profile", Name: "dev-org"}).WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	wantDOT := `digraph aws_config {
+  rankdir=LR;
+  "profile dev" [label="dev", shape=box];
+  "profile dev-org" [label="dev-org", shape=box];
+  "services dev-endpoints" [label="dev-endpoints", shape=component];
+  "sso-session corp" [label="corp", shape=ellipse];
+  "profile dev" -> "services dev-endpoints" [label="services"];
+  "profile dev" -> "sso-session corp" [label="sso_session"];
+  "profile dev-org" -> "profile dev" [label="source_profile"];
+}
+`
+	if b.String() != wantDOT {
+		t.Errorf("WriteDOT() =\n%s\nwant\n%s", b.String(), wantDOT)
+	}
+
+	b.Reset()
+	if err := g.Reachable(Node{Type: "profile", Name: "x"}, Node{Type: "profile", Name: "lost"}).WriteMermaid(&b); err != nil {
+		t.Fatal(err)
+	}
+	wantMermaid := `flowchart LR
+  n0["lost"]
+  n1["x"]
+  n2["y"]
+  n3(["gone (not defined)"])
+  n0 -->|sso_session| n3
+  n1 -->|source_profile| n2
+  n2 -->|source_profile| n1
+  classDef missing stroke:#d00,color:#d00,stroke-dasharray:4
+  class n3 missing
+  linkStyle 0,1,2 stroke:#d00
+`
+	if b.String() != wantMermaid {
+		t.Errorf("WriteMermaid() =\n%s\nwant\n%s", b.String(), wantMermaid)
+	}
+}
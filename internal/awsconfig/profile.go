@@ -1,17 +1,51 @@
 package awsconfig
 
 import (
+	"maps"
+	"slices"
+	"strings"
+
 	"charm.land/bubbles/v2/table"
 	"gopkg.in/ini.v1"
 )
 
+// Profile is a profile section in the AWS config file. Keys starting with
+// wasp_ are owned by wasp and ignored by the AWS CLI.
 type Profile struct {
-	Name        string      `ini:"-"`
-	Session     *SSOSession `ini:"-"`
-	SSOSession  string      `ini:"sso_session"`
-	AccountName string      `ini:"-"`
-	AccountID   string      `ini:"sso_account_id"`
-	RoleName    string      `ini:"sso_role_name"`
+	Name       string      `ini:"-"`
+	Session    *SSOSession `ini:"-"`
+	SSOSession string      `ini:"sso_session"`
+	// AccountName and AccountEmail let wasp show account names without
+	// calling AWS SSO
+	AccountName  string `ini:"wasp_account_name"`
+	AccountEmail string `ini:"wasp_account_email"`
+	AccountID    string `ini:"sso_account_id"`
+	RoleName     string `ini:"sso_role_name"`
+	// RoleARN, SourceProfile and CredentialSource are set on profiles that
+	// assume a role
+	RoleARN          string `ini:"role_arn"`
+	SourceProfile    string `ini:"source_profile"`
+	CredentialSource string `ini:"credential_source"`
+	// Services names a services section of endpoint settings
+	Services string `ini:"services"`
+	// AliasFor marks a profile written for an alias
+	AliasFor string `ini:"wasp_alias_for"`
+	// Managed marks profiles wasp created or adopted; sync leaves every
+	// other profile alone
+	Managed bool `ini:"wasp_managed"`
+	// Manifest names the team manifest that created the profile
+	Manifest string `ini:"wasp_manifest"`
+	// NoDefaults keeps a new section from starting as a copy of the
+	// default profile
+	NoDefaults bool `ini:"-"`
+	// Generated marks a profile wasp has just written in full: its keys
+	// are recorded in wasp_keys, and keys recorded there before that it no
+	// longer sets are removed. Sections written before wasp_keys only lose
+	// the keys that say how to sign in.
+	Generated bool `ini:"-"`
+	// Settings are any other keys to write, such as region
+	Settings map[string]string `ini:"-"`
+	Source   string            `ini:"-"`
 }
 
 func NewProfile(name string) *Profile {
@@ -21,6 +55,81 @@ func NewProfile(name string) *Profile {
 	}
 }
 
+// KeyValue is a key in a config file section
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// keys returns the keys Update writes to the profile's section, settings
+// last and sorted by key
+func (p *Profile) keys() []KeyValue {
+	var kvs []KeyValue
+	for _, kv := range []KeyValue{
+		{"sso_session", p.SSOSession},
+		{"sso_account_id", p.AccountID},
+		{"sso_role_name", p.RoleName},
+		{"role_arn", p.RoleARN},
+		{"source_profile", p.SourceProfile},
+		{"credential_source", p.CredentialSource},
+		{"services", p.Services},
+		{"wasp_account_name", p.AccountName},
+		{"wasp_account_email", p.AccountEmail},
+		{"wasp_alias_for", p.AliasFor},
+		{"wasp_manifest", p.Manifest},
+	} {
+		if kv.Value != "" {
+			kvs = append(kvs, kv)
+		}
+	}
+	if p.Managed {
+		kvs = append(kvs, KeyValue{"wasp_managed", "true"})
+	}
+	var settings []KeyValue
+	for _, key := range slices.Sorted(maps.Keys(p.Settings)) {
+		settings = append(settings, KeyValue{key, p.Settings[key]})
+	}
+	if p.Generated {
+		var owned []string
+		for _, kv := range append(slices.Clone(kvs), settings...) {
+			if !strings.HasPrefix(kv.Key, "wasp_") {
+				owned = append(owned, kv.Key)
+			}
+		}
+		slices.Sort(owned)
+		kvs = append(kvs, KeyValue{"wasp_keys", strings.Join(owned, ",")})
+	}
+	kvs = append(kvs, settings...)
+	return kvs
+}
+
+// credentialKeys are the keys that say how a profile signs in
+var credentialKeys = []string{"sso_session", "sso_account_id", "sso_role_name", "role_arn", "source_profile", "credential_source"}
+
+// removedKeys returns the keys of a section, given as old, that wasp wrote
+// before but the generated profile no longer sets, sorted
+func (p *Profile) removedKeys(old map[string]string) []string {
+	if !p.Generated {
+		return nil
+	}
+	owned := credentialKeys
+	if old["wasp_keys"] != "" {
+		owned = strings.Split(old["wasp_keys"], ",")
+	}
+	current := make(map[string]bool)
+	for _, kv := range p.keys() {
+		current[kv.Key] = true
+	}
+	var removed []string
+	for _, key := range owned {
+		if _, ok := old[key]; ok && !current[key] && !slices.Contains(removed, key) {
+			removed = append(removed, key)
+		}
+	}
+	slices.Sort(removed)
+	return removed
+}
+
 func (p *Profile) colWidths() map[string]int {
 	return map[string]int{
 		"profile_name": len(p.Name),
@@ -35,6 +144,17 @@ func (p *Profile) Type() string {
 	return "profile"
 }
 
+// TableRow returns the profile's values in TableColumns order
+func (p *Profile) TableRow() table.Row {
+	return table.Row{
+		p.Name,
+		p.AccountName,
+		p.SSOSession,
+		p.AccountID,
+		p.RoleName,
+	}
+}
+
 type Profiles struct {
 	m         map[string]*Profile
 	colWidths map[string]int
@@ -54,15 +174,26 @@ func (p *Profiles) NewFromSection(name string, section *ini.Section) error {
 		return err
 	}
 	p.m[name] = profile
+	p.updateColWidths(profile)
 
-	// Update column widths for profiles
+	return nil
+}
+
+// UpdateColWidths recalculates the table column widths after profiles have
+// been changed in place.
+func (p *Profiles) UpdateColWidths() {
+	p.colWidths = make(map[string]int)
+	for _, profile := range p.m {
+		p.updateColWidths(profile)
+	}
+}
+
+func (p *Profiles) updateColWidths(profile *Profile) {
 	for k, v := range profile.colWidths() {
 		if p.colWidths[k] < v {
 			p.colWidths[k] = v
 		}
 	}
-
-	return nil
 }
 
 func (p *Profiles) Name(name string) *Profile {
@@ -84,13 +215,7 @@ func (p *Profiles) Map() map[string]*Profile {
 func (p *Profiles) TableModel(maxRows int) table.Model {
 	var rows []table.Row
 	for _, profile := range p.m {
-		rows = append(rows, table.Row{
-			profile.Name,
-			profile.AccountName,
-			profile.SSOSession,
-			profile.AccountID,
-			profile.RoleName,
-		})
+		rows = append(rows, profile.TableRow())
 	}
 
 	return table.New(
@@ -0,0 +1,106 @@
+package awsconfig
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// RawSection is a section as it's written in a config file. ini merges
+// sections and keys that appear more than once, so these are what shows
+// the duplicates.
+type RawSection struct {
+	File string
+	// Header is the text between the brackets, e.g. "profile dev"
+	Header string
+	// Type is profile, sso-session or services, and Name the rest of the
+	// header
+	Type string
+	Name string
+	Line int
+	Keys []RawKey
+}
+
+// RawKey is a key as it's written in a section. Nested keys, such as the
+// ones under s3, are left out.
+type RawKey struct {
+	Name  string
+	Value string
+	Line  int
+}
+
+// Values returns every value the key is set to in the section
+func (s RawSection) Values(key string) []RawKey {
+	var keys []RawKey
+	for _, k := range s.Keys {
+		if k.Name == key {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// parseRaw splits a config file into its sections
+func parseRaw(path string, data []byte) []RawSection {
+	var sections []RawSection
+	var current *RawSection
+	nested := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";"):
+			continue
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			header := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			sectionType, name := splitSectionText(header)
+			sections = append(sections, RawSection{File: path, Header: header, Type: sectionType, Name: name, Line: line})
+			current = &sections[len(sections)-1]
+			nested = false
+		case current == nil:
+			continue
+		case nested && text != trimmed:
+			// Indented keys belong to the key above, e.g. s3 settings
+			continue
+		default:
+			key, value, _ := strings.Cut(trimmed, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			current.Keys = append(current.Keys, RawKey{key, value, line})
+			nested = value == ""
+		}
+	}
+	return sections
+}
+
+// RawSections returns the sections of every loaded file, in file order
+func (cf *ConfigFile) RawSections() []RawSection {
+	var sections []RawSection
+	for _, src := range cf.sources {
+		sections = append(sections, src.raw...)
+	}
+	return sections
+}
+
+// SetKey sets a key in a section of the file it was read from, to be
+// written by Update
+func (cf *ConfigFile) SetKey(s RawSection, key, value string) {
+	src := cf.source(s.File)
+	src.dirty = setKey(src.iniFile.Section(s.Header), key, value) || src.dirty
+}
+
+// DeleteKey removes a key from a section of the file it was read from, to
+// be written by Update
+func (cf *ConfigFile) DeleteKey(s RawSection, key string) {
+	src := cf.source(s.File)
+	if section, err := src.iniFile.GetSection(s.Header); err == nil && section.HasKey(key) {
+		section.DeleteKey(key)
+		src.dirty = true
+	}
+}
+
+// Rewrite has Update write a file even if nothing in it changed, merging
+// sections that appear more than once
+func (cf *ConfigFile) Rewrite(file string) {
+	cf.source(file).dirty = true
+}
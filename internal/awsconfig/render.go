@@ -0,0 +1,202 @@
+package awsconfig
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// WriteTree writes the graph as a tree of what profiles get their
+// credentials from: each SSO session and credential source with the
+// profiles using it, and profiles under the profile they assume a role
+// from. Broken references are marked ✗ and cycles ↻.
+func (g *Graph) WriteTree(w io.Writer) error {
+	dependents := make(map[Node][]Node)
+	hasRoot := make(map[Node]bool)
+	var roots []Node
+	for _, e := range g.Edges {
+		if e.Key == "services" || e.Cycle || e.From == e.To {
+			continue
+		}
+		if e.Key == "source_profile" || e.Key == "sso_session" || e.Key == "credential_source" {
+			dependents[e.To] = append(dependents[e.To], e.From)
+			hasRoot[e.From] = true
+		}
+	}
+	inCycle := make(map[string]bool)
+	for _, cycle := range g.Cycles {
+		for _, name := range cycle {
+			inCycle[name] = true
+		}
+	}
+	for _, n := range g.Nodes {
+		switch {
+		case n.Type == ServicesNode:
+		case n.Type == "profile" && (hasRoot[n] || inCycle[n.Name]) && !n.Missing:
+		case n.Type == "profile" && !n.Missing:
+			roots = append(roots, n)
+		case len(dependents[n]) > 0 || n.Type == "sso-session":
+			roots = append(roots, n)
+		}
+	}
+	// Sessions and credential sources first, then profiles with their own
+	// credentials
+	slices.SortStableFunc(roots, func(a, b Node) int {
+		rank := func(n Node) int {
+			if n.Type == "profile" && !n.Missing {
+				return 1
+			}
+			return 0
+		}
+		return rank(a) - rank(b)
+	})
+
+	services := make(map[Node][]Node)
+	for _, e := range g.Edges {
+		if e.Key == "services" {
+			services[e.From] = append(services[e.From], e.To)
+		}
+	}
+	label := func(n Node) string {
+		text := n.Name
+		if n.Type != "profile" || n.Missing {
+			text = n.String()
+		}
+		if n.Missing {
+			text += " ✗ not defined"
+			if n.Type == CredentialSourceNode {
+				text = n.String() + " ✗ unknown"
+			}
+		}
+		for _, s := range services[n] {
+			text += " [services " + s.Name
+			if s.Missing {
+				text += " ✗ not defined"
+			}
+			text += "]"
+		}
+		return text
+	}
+
+	var b strings.Builder
+	var walk func(n Node, prefix string, seen map[Node]bool)
+	walk = func(n Node, prefix string, seen map[Node]bool) {
+		children := dependents[n]
+		for i, child := range children {
+			branch, next := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, next = "└── ", "    "
+			}
+			b.WriteString(prefix + branch + label(child) + "\n")
+			if !seen[child] {
+				seen[child] = true
+				walk(child, prefix+next, seen)
+			}
+		}
+	}
+	for _, root := range roots {
+		b.WriteString(label(root) + "\n")
+		walk(root, "", map[Node]bool{root: true})
+	}
+	for _, cycle := range g.Cycles {
+		b.WriteString("↻ " + (&CycleError{cycle}).Error() + "\n")
+		for i, name := range cycle {
+			n := Node{Type: "profile", Name: name}
+			branch, next := "├── ", "│   "
+			if i == len(cycle)-1 {
+				branch, next = "└── ", "    "
+			}
+			b.WriteString(branch + label(n) + "\n")
+			walk(n, next, map[Node]bool{n: true})
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotShapes are the Graphviz shapes for each node type
+var dotShapes = map[string]string{
+	"profile":            "box",
+	"sso-session":        "ellipse",
+	ServicesNode:         "component",
+	CredentialSourceNode: "diamond",
+}
+
+// WriteDOT writes the graph in Graphviz DOT. Broken references and cycles
+// are red.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph aws_config {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=%s, shape=%s", dotQuote(n.Name), dotShapes[n.Type])
+		if n.Missing {
+			attrs = fmt.Sprintf("label=%s, shape=%s, color=red, fontcolor=red, style=dashed", dotQuote(n.Name+"\n(not defined)"), dotShapes[n.Type])
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.String()), attrs)
+	}
+	for _, e := range g.Edges {
+		attrs := fmt.Sprintf("label=%s", dotQuote(e.Key))
+		if e.Cycle || e.To.Missing {
+			attrs += ", color=red, fontcolor=red"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.From.String()), dotQuote(e.To.String()), attrs)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// mermaidShapes are the Mermaid flowchart shapes for each node type
+var mermaidShapes = map[string][2]string{
+	"profile":            {"[", "]"},
+	"sso-session":        {"([", "])"},
+	ServicesNode:         {"[[", "]]"},
+	CredentialSourceNode: {"{{", "}}"},
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. Broken references
+// and cycles are red.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[Node]string)
+	var missing []string
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n] = id
+		label := n.Name
+		if n.Missing {
+			label += " (not defined)"
+			missing = append(missing, id)
+		}
+		shape := mermaidShapes[n.Type]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", id, shape[0], mermaidQuote(label), shape[1])
+	}
+	var red []string
+	for i, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], e.Key, ids[e.To])
+		if e.Cycle || e.To.Missing {
+			red = append(red, fmt.Sprint(i))
+		}
+	}
+	if len(missing) > 0 {
+		b.WriteString("  classDef missing stroke:#d00,color:#d00,stroke-dasharray:4\n")
+		fmt.Fprintf(&b, "  class %s missing\n", strings.Join(missing, ","))
+	}
+	if len(red) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#d00\n", strings.Join(red, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -4,7 +4,8 @@ import "gopkg.in/ini.v1"
 
 // Unimplemented past name
 type Service struct {
-	Name string
+	Name   string
+	Source string `ini:"-"`
 }
 
 func NewService(name string) *Service {
@@ -14,6 +14,7 @@ type SSOSession struct {
 	AccountID          string   `ini:"sso_account_id,omitempty"`
 	RoleName           string   `ini:"sso_role_name,omitempty"`
 	RegistrationScopes []string `ini:"sso_registration_scopes,omitempty"`
+	Source             string   `ini:"-"`
 }
 
 func NewSSOSession(name string) *SSOSession {
@@ -22,6 +23,18 @@ func NewSSOSession(name string) *SSOSession {
 	}
 }
 
+// keys returns the keys Update writes to the session's section
+func (s *SSOSession) keys() []KeyValue {
+	kvs := []KeyValue{
+		{"sso_start_url", s.StartURL},
+		{"sso_region", s.Region},
+	}
+	if len(s.RegistrationScopes) > 0 {
+		kvs = append(kvs, KeyValue{"sso_registration_scopes", strings.Join(s.RegistrationScopes, ",")})
+	}
+	return kvs
+}
+
 func (s *SSOSession) colWidths() map[string]int {
 	return map[string]int{
 		"name":                    len(s.Name),
@@ -107,3 +120,36 @@ func (s *SSOSessions) TableColumns() []table.Column {
 		{Title: "Region", Width: s.colWidths["sso_region"]},
 	}
 }
+
+// TokenOwner is the SSO session, or the profile with legacy SSO settings,
+// that a cached SSO token belongs to
+type TokenOwner struct {
+	Name   string
+	Legacy bool
+}
+
+// TokenOwners maps the keys the SSO token cache names files after to their
+// owners. Sessions are keyed by name and by start URL, as tokens from
+// before sso-session sections are, and profiles that set sso_start_url
+// without a session by their start URL.
+func (cf ConfigFile) TokenOwners() map[string]TokenOwner {
+	owners := make(map[string]TokenOwner)
+	for _, p := range cf.Profiles.List() {
+		if p.SSOSession != "" {
+			continue
+		}
+		url := cf.ProfileKeys(p.Name)["sso_start_url"]
+		if owner, ok := owners[url]; url != "" && (!ok || p.Name < owner.Name) {
+			owners[url] = TokenOwner{Name: p.Name, Legacy: true}
+		}
+	}
+	for name, session := range cf.SSOSessions.Map() {
+		if session.StartURL != "" {
+			owners[session.StartURL] = TokenOwner{Name: name, Legacy: true}
+		}
+	}
+	for name := range cf.SSOSessions.Map() {
+		owners[name] = TokenOwner{Name: name}
+	}
+	return owners
+}
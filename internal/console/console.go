@@ -0,0 +1,159 @@
+// Package console builds AWS console sign-in URLs from temporary
+// credentials with the AWS federation endpoint.
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Doer sends HTTP requests; *http.Client is one
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Partition is where an AWS partition's federation endpoint and console
+// live
+type Partition struct {
+	Endpoint string
+	Console  string
+}
+
+var (
+	// AWS is the commercial partition
+	AWS = Partition{"https://signin.aws.amazon.com/federation", "https://console.aws.amazon.com"}
+	// GovCloud is the AWS GovCloud (US) partition
+	GovCloud = Partition{"https://signin.amazonaws-us-gov.com/federation", "https://console.amazonaws-us-gov.com"}
+	// China is the AWS China partition
+	China = Partition{"https://signin.amazonaws.cn/federation", "https://console.amazonaws.cn"}
+)
+
+// PartitionFor returns the partition a region is in
+func PartitionFor(region string) Partition {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return GovCloud
+	case strings.HasPrefix(region, "cn-"):
+		return China
+	}
+	return AWS
+}
+
+// Destination returns the console page for a service, e.g. "ec2" or
+// "s3/buckets", in a region. Either can be empty; a service that's already
+// a URL is returned as it is.
+func (p Partition) Destination(service, region string) string {
+	if strings.Contains(service, "://") {
+		return service
+	}
+	if service == "" && region == "" {
+		return p.Console + "/"
+	}
+	if service == "" {
+		service = "console"
+	}
+	path := strings.Trim(service, "/")
+	if !strings.Contains(path, "/") {
+		path += "/home"
+	}
+	dest := p.Console + "/" + path
+	if region != "" {
+		dest += "?" + url.Values{"region": {region}}.Encode()
+	}
+	return dest
+}
+
+// Client signs in to the console through a federation endpoint
+type Client struct {
+	// HTTP sends the getSigninToken request, http.DefaultClient if nil
+	HTTP Doer
+
+	// Endpoint is the federation endpoint, AWS.Endpoint if empty
+	Endpoint string
+
+	// Issuer is where the console sends users whose session has expired
+	Issuer string
+}
+
+// SigninToken exchanges temporary credentials for a sign-in token
+func (c *Client) SigninToken(ctx context.Context, creds aws.Credentials) (string, error) {
+	if creds.SessionToken == "" {
+		return "", errors.New("console sign-in needs temporary credentials with a session token")
+	}
+	session, err := json.Marshal(map[string]string{
+		"sessionId":    creds.AccessKeyID,
+		"sessionKey":   creds.SecretAccessKey,
+		"sessionToken": creds.SessionToken,
+	})
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"Action": {"getSigninToken"}, "Session": {string(session)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint()+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("getting a sign-in token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("getting a sign-in token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting a sign-in token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		SigninToken string
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("getting a sign-in token: %w", err)
+	}
+	if out.SigninToken == "" {
+		return "", errors.New("getting a sign-in token: the response has no SigninToken")
+	}
+	return out.SigninToken, nil
+}
+
+// LoginURL returns the URL that signs in with a sign-in token and opens
+// destination
+func (c *Client) LoginURL(token, destination string) string {
+	query := url.Values{"Action": {"login"}, "Destination": {destination}, "SigninToken": {token}}
+	if c.Issuer != "" {
+		query.Set("Issuer", c.Issuer)
+	}
+	return c.endpoint() + "?" + query.Encode()
+}
+
+// URL signs in with temporary credentials and returns the login URL for
+// destination
+func (c *Client) URL(ctx context.Context, creds aws.Credentials, destination string) (string, error) {
+	token, err := c.SigninToken(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	return c.LoginURL(token, destination), nil
+}
+
+func (c *Client) endpoint() string {
+	if c.Endpoint == "" {
+		return AWS.Endpoint
+	}
+	return c.Endpoint
+}
@@ -0,0 +1,100 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+var testCreds = aws.Credentials{AccessKeyID: "ASIA111111111111", SecretAccessKey: "secret", SessionToken: "token"}
+
+func TestURL(t *testing.T) {
+	var session map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("Action") != "getSigninToken" {
+			http.Error(w, "bad action", http.StatusBadRequest)
+			return
+		}
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("Session")), &session); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"SigninToken":"signin-123"}`))
+	}))
+	defer server.Close()
+
+	c := &Client{HTTP: server.Client(), Endpoint: server.URL + "/federation", Issuer: "wasp"}
+	got, err := c.URL(context.Background(), testCreds, AWS.Destination("ec2", "eu-west-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"sessionId": "ASIA111111111111", "sessionKey": "secret", "sessionToken": "token"}
+	for key, value := range want {
+		if session[key] != value {
+			t.Errorf("session %s = %q, want %q", key, session[key], value)
+		}
+	}
+
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if !strings.HasPrefix(got, server.URL+"/federation?") || query.Get("Action") != "login" || query.Get("SigninToken") != "signin-123" || query.Get("Issuer") != "wasp" {
+		t.Errorf("URL = %s", got)
+	}
+	if dest := query.Get("Destination"); dest != "https://console.aws.amazon.com/ec2/home?region=eu-west-1" {
+		t.Errorf("Destination = %s", dest)
+	}
+}
+
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSigninTokenErrors(t *testing.T) {
+	c := &Client{HTTP: doerFunc(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasPrefix(req.URL.String(), AWS.Endpoint+"?") {
+			t.Errorf("request to %s, want the AWS endpoint", req.URL)
+		}
+		return &http.Response{Status: "400 Bad Request", StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader("expired\n"))}, nil
+	})}
+	if _, err := c.SigninToken(context.Background(), testCreds); err == nil || err.Error() != "getting a sign-in token: 400 Bad Request: expired" {
+		t.Errorf("SigninToken() error = %v", err)
+	}
+
+	longTerm := aws.Credentials{AccessKeyID: "AKIA111111111111", SecretAccessKey: "secret"}
+	if _, err := c.SigninToken(context.Background(), longTerm); err == nil {
+		t.Error("SigninToken() accepted credentials without a session token")
+	}
+}
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		partition       Partition
+		service, region string
+		want            string
+	}{
+		{AWS, "", "", "https://console.aws.amazon.com/"},
+		{AWS, "", "us-east-1", "https://console.aws.amazon.com/console/home?region=us-east-1"},
+		{AWS, "s3", "", "https://console.aws.amazon.com/s3/home"},
+		{AWS, "s3/buckets", "", "https://console.aws.amazon.com/s3/buckets"},
+		{AWS, "https://example.com/x", "us-east-1", "https://example.com/x"},
+		{PartitionFor("us-gov-west-1"), "ec2", "us-gov-west-1", "https://console.amazonaws-us-gov.com/ec2/home?region=us-gov-west-1"},
+		{PartitionFor("cn-north-1"), "", "", "https://console.amazonaws.cn/"},
+	}
+	for _, tt := range tests {
+		if got := tt.partition.Destination(tt.service, tt.region); got != tt.want {
+			t.Errorf("Destination(%q, %q) = %q, want %q", tt.service, tt.region, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Package export writes the profiles in the AWS config file in the formats
+// other tools keep their own AWS settings in.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+)
+
+// Format is a tool's configuration format
+type Format string
+
+const (
+	Granted   Format = "granted"
+	AWSVault  Format = "aws-vault"
+	Terraform Format = "terraform"
+	Steampipe Format = "steampipe"
+	JSON      Format = "json"
+)
+
+// Formats lists every supported format
+var Formats = []Format{Granted, AWSVault, Terraform, Steampipe, JSON}
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of: %s", s, strings.Join(names, ", "))
+}
+
+const header = "Generated by wasp export from the AWS config file; changes are overwritten."
+
+// Profile is a profile with its SSO settings resolved, from its sso-session
+// section or the legacy keys. AccountID is taken from RoleARN for profiles
+// that assume a role.
+type Profile struct {
+	Name             string `json:"name" yaml:"name"`
+	SSOSession       string `json:"sso_session,omitempty" yaml:"sso_session,omitempty"`
+	StartURL         string `json:"sso_start_url,omitempty" yaml:"sso_start_url,omitempty"`
+	SSORegion        string `json:"sso_region,omitempty" yaml:"sso_region,omitempty"`
+	AccountID        string `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	AccountName      string `json:"account_name,omitempty" yaml:"account_name,omitempty"`
+	RoleName         string `json:"role_name,omitempty" yaml:"role_name,omitempty"`
+	RoleARN          string `json:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+	SourceProfile    string `json:"source_profile,omitempty" yaml:"source_profile,omitempty"`
+	CredentialSource string `json:"credential_source,omitempty" yaml:"credential_source,omitempty"`
+	Region           string `json:"region,omitempty" yaml:"region,omitempty"`
+	Managed          bool   `json:"managed" yaml:"managed"`
+
+	keys map[string]string
+}
+
+// SSO reports whether the profile signs in with AWS SSO
+func (p Profile) SSO() bool {
+	return p.StartURL != "" && p.AccountID != "" && p.RoleName != "" && p.RoleARN == ""
+}
+
+// Session is an SSO session in the JSON inventory
+type Session struct {
+	Name               string   `json:"name" yaml:"name"`
+	StartURL           string   `json:"start_url" yaml:"start_url"`
+	Region             string   `json:"region" yaml:"region"`
+	RegistrationScopes []string `json:"registration_scopes,omitempty" yaml:"registration_scopes,omitempty"`
+}
+
+// Inventory is the JSON export: the profiles and the SSO sessions they use.
+// wasp import reads it back, as JSON or YAML.
+type Inventory struct {
+	SSOSessions []Session `json:"sso_sessions" yaml:"sso_sessions"`
+	Profiles    []Profile `json:"profiles" yaml:"profiles"`
+}
+
+// Resolve resolves profiles for export, sorted by name
+func Resolve(cf *awsconfig.ConfigFile, profiles []*awsconfig.Profile) []Profile {
+	var ret []Profile
+	for _, p := range profiles {
+		keys := cf.ProfileKeys(p.Name)
+		e := Profile{
+			Name:             p.Name,
+			SSOSession:       p.SSOSession,
+			StartURL:         keys["sso_start_url"],
+			SSORegion:        keys["sso_region"],
+			AccountID:        p.AccountID,
+			AccountName:      p.AccountName,
+			RoleName:         p.RoleName,
+			RoleARN:          p.RoleARN,
+			SourceProfile:    p.SourceProfile,
+			CredentialSource: p.CredentialSource,
+			Region:           keys["region"],
+			Managed:          p.Managed,
+			keys:             keys,
+		}
+		if session := cf.SSOSessions.Name(p.SSOSession); session != nil {
+			e.StartURL, e.SSORegion = session.StartURL, session.Region
+		}
+		if accountID, roleName, ok := awsconfig.ParseRoleARN(p.RoleARN); ok {
+			e.AccountID, e.RoleName = accountID, roleName
+		}
+		ret = append(ret, e)
+	}
+	slices.SortFunc(ret, func(a, b Profile) int { return strings.Compare(a.Name, b.Name) })
+	return ret
+}
+
+// Write writes profiles in the given format
+func Write(w io.Writer, format Format, cf *awsconfig.ConfigFile, profiles []*awsconfig.Profile) error {
+	resolved := Resolve(cf, profiles)
+	switch format {
+	case Granted:
+		return writeGranted(w, resolved)
+	case AWSVault:
+		return writeAWSVault(w, resolved)
+	case Terraform:
+		return writeTerraform(w, resolved)
+	case Steampipe:
+		return writeSteampipe(w, resolved)
+	case JSON:
+		return writeJSON(w, cf, resolved)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// writeGranted writes an AWS config file in the form granted sso generate
+// writes: SSO profiles get credentials from granted credential-process
+// rather than from the AWS CLI's SSO support
+func writeGranted(w io.Writer, profiles []Profile) error {
+	return writeINI(w, profiles, func(p Profile) []awsconfig.KeyValue {
+		return []awsconfig.KeyValue{
+			{Key: "granted_sso_start_url", Value: p.StartURL},
+			{Key: "granted_sso_region", Value: p.SSORegion},
+			{Key: "granted_sso_account_id", Value: p.AccountID},
+			{Key: "granted_sso_role_name", Value: p.RoleName},
+			{Key: "common_fate_generated_from", Value: "aws-sso"},
+			{Key: "credential_process", Value: "granted credential-process --profile " + shellQuote(p.Name)},
+		}
+	})
+}
+
+// writeAWSVault writes an AWS config file with the legacy SSO keys in every
+// SSO profile, which every aws-vault version understands
+func writeAWSVault(w io.Writer, profiles []Profile) error {
+	return writeINI(w, profiles, func(p Profile) []awsconfig.KeyValue {
+		return []awsconfig.KeyValue{
+			{Key: "sso_start_url", Value: p.StartURL},
+			{Key: "sso_region", Value: p.SSORegion},
+			{Key: "sso_account_id", Value: p.AccountID},
+			{Key: "sso_role_name", Value: p.RoleName},
+		}
+	})
+}
+
+// writeINI writes profiles as AWS config file sections, replacing the SSO
+// keys of SSO profiles with the ones sso returns. Every other key is kept
+// except wasp's own.
+func writeINI(w io.Writer, profiles []Profile, sso func(Profile) []awsconfig.KeyValue) error {
+	if _, err := fmt.Fprintf(w, "# %s\n", header); err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		var kvs []awsconfig.KeyValue
+		if p.SSO() {
+			kvs = sso(p)
+		}
+		for _, key := range slices.Sorted(maps.Keys(p.keys)) {
+			if strings.HasPrefix(key, "wasp_") || p.SSO() && strings.HasPrefix(key, "sso_") {
+				continue
+			}
+			kvs = append(kvs, awsconfig.KeyValue{Key: key, Value: p.keys[key]})
+		}
+		section := "profile " + p.Name
+		if p.Name == "default" {
+			section = "default"
+		}
+		if _, err := fmt.Fprintf(w, "\n[%s]\n", section); err != nil {
+			return err
+		}
+		for _, kv := range kvs {
+			if _, err := fmt.Fprintf(w, "%s = %s\n", kv.Key, kv.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeTerraform writes an aliased aws provider block for each profile
+func writeTerraform(w io.Writer, profiles []Profile) error {
+	if _, err := fmt.Fprintf(w, "# %s\n", header); err != nil {
+		return err
+	}
+	ids := identifiers(profiles, "")
+	for _, p := range profiles {
+		attrs := []awsconfig.KeyValue{
+			{Key: "alias", Value: hclQuote(ids[p.Name])},
+			{Key: "profile", Value: hclQuote(p.Name)},
+		}
+		if p.Region != "" {
+			attrs = append(attrs, awsconfig.KeyValue{Key: "region", Value: hclQuote(p.Region)})
+		}
+		if err := writeBlock(w, describe(p), `provider "aws"`, attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSteampipe writes an aws plugin connection for each profile and an
+// aggregator named aws that queries all of them
+func writeSteampipe(w io.Writer, profiles []Profile) error {
+	if _, err := fmt.Fprintf(w, "# %s\n", header); err != nil {
+		return err
+	}
+	ids := identifiers(profiles, "aws_")
+	for _, p := range profiles {
+		attrs := []awsconfig.KeyValue{
+			{Key: "plugin", Value: hclQuote("aws")},
+			{Key: "profile", Value: hclQuote(p.Name)},
+		}
+		if p.Region != "" {
+			attrs = append(attrs, awsconfig.KeyValue{Key: "regions", Value: "[" + hclQuote(p.Region) + "]"})
+		}
+		if err := writeBlock(w, describe(p), "connection "+hclQuote(ids[p.Name]), attrs); err != nil {
+			return err
+		}
+	}
+	return writeBlock(w, "All of the above", "connection "+hclQuote("aws"), []awsconfig.KeyValue{
+		{Key: "plugin", Value: hclQuote("aws")},
+		{Key: "type", Value: hclQuote("aggregator")},
+		{Key: "connections", Value: "[" + hclQuote("aws_*") + "]"},
+	})
+}
+
+// writeBlock writes an HCL block with its attributes aligned the way
+// terraform fmt aligns them
+func writeBlock(w io.Writer, comment, block string, attrs []awsconfig.KeyValue) error {
+	width := 0
+	for _, a := range attrs {
+		width = max(width, len(a.Key))
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	if comment != "" {
+		fmt.Fprintf(&b, "# %s\n", comment)
+	}
+	fmt.Fprintf(&b, "%s {\n", block)
+	for _, a := range attrs {
+		fmt.Fprintf(&b, "  %-*s = %s\n", width, a.Key, a.Value)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeJSON writes the profiles and the SSO sessions they use
+func writeJSON(w io.Writer, cf *awsconfig.ConfigFile, profiles []Profile) error {
+	inventory := Inventory{SSOSessions: []Session{}, Profiles: profiles}
+	if profiles == nil {
+		inventory.Profiles = []Profile{}
+	}
+	used := make(map[string]bool)
+	for _, p := range profiles {
+		used[p.SSOSession] = true
+	}
+	for _, s := range cf.SSOSessions.List() {
+		if used[s.Name] {
+			inventory.SSOSessions = append(inventory.SSOSessions, Session{s.Name, s.StartURL, s.Region, s.RegistrationScopes})
+		}
+	}
+	slices.SortFunc(inventory.SSOSessions, func(a, b Session) int { return strings.Compare(a.Name, b.Name) })
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inventory)
+}
+
+// describe says which account and role a profile signs in to
+func describe(p Profile) string {
+	var parts []string
+	switch {
+	case p.AccountName != "" && p.AccountID != "":
+		parts = append(parts, fmt.Sprintf("%s (%s)", p.AccountName, p.AccountID))
+	case p.AccountID != "":
+		parts = append(parts, p.AccountID)
+	}
+	if p.RoleName != "" {
+		parts = append(parts, p.RoleName)
+	}
+	return strings.Join(parts, ", ")
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9]+`)
+
+// identifiers derives a lowercase identifier from each profile name, made
+// unique with a numeric suffix
+func identifiers(profiles []Profile, prefix string) map[string]string {
+	ids := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, p := range profiles {
+		id := strings.Trim(nonIdentifier.ReplaceAllString(strings.ToLower(p.Name), "_"), "_")
+		if id == "" || prefix == "" && id[0] >= '0' && id[0] <= '9' {
+			id = "profile_" + id
+		}
+		id = prefix + strings.TrimSuffix(id, "_")
+		unique := id
+		for n := 2; taken[unique]; n++ {
+			unique = id + "_" + strconv.Itoa(n)
+		}
+		taken[unique] = true
+		ids[p.Name] = unique
+	}
+	return ids
+}
+
+// hclQuote quotes a string for HCL, escaping template sequences
+func hclQuote(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+// shellQuote quotes a profile name for a credential_process command line
+func shellQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,213 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+)
+
+const testConfig = `[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+
+[profile Acme Production_Admin]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+wasp_account_name = Acme Production
+wasp_managed = true
+region = eu-west-1
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-west-2
+sso_account_id = 222222222222
+sso_role_name = ReadOnly
+
+[profile Acme Production_OrgAdmin]
+role_arn = arn:aws:iam::333333333333:role/OrganizationAccountAccessRole
+source_profile = Acme Production_Admin
+duration_seconds = 7200
+`
+
+func loadTestConfig(t *testing.T) *awsconfig.ConfigFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := awsconfig.NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cf
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{Granted, `# Generated by wasp export from the AWS config file; changes are overwritten.
+
+[profile Acme Production_Admin]
+granted_sso_start_url = https://corp.awsapps.com/start
+granted_sso_region = us-east-1
+granted_sso_account_id = 111111111111
+granted_sso_role_name = Admin
+common_fate_generated_from = aws-sso
+credential_process = granted credential-process --profile "Acme Production_Admin"
+region = eu-west-1
+
+[profile Acme Production_OrgAdmin]
+duration_seconds = 7200
+role_arn = arn:aws:iam::333333333333:role/OrganizationAccountAccessRole
+source_profile = Acme Production_Admin
+
+[profile legacy]
+granted_sso_start_url = https://legacy.awsapps.com/start
+granted_sso_region = us-west-2
+granted_sso_account_id = 222222222222
+granted_sso_role_name = ReadOnly
+common_fate_generated_from = aws-sso
+credential_process = granted credential-process --profile legacy
+`},
+		{AWSVault, `# Generated by wasp export from the AWS config file; changes are overwritten.
+
+[profile Acme Production_Admin]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 111111111111
+sso_role_name = Admin
+region = eu-west-1
+
+[profile Acme Production_OrgAdmin]
+duration_seconds = 7200
+role_arn = arn:aws:iam::333333333333:role/OrganizationAccountAccessRole
+source_profile = Acme Production_Admin
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-west-2
+sso_account_id = 222222222222
+sso_role_name = ReadOnly
+`},
+		{Terraform, `# Generated by wasp export from the AWS config file; changes are overwritten.
+
+# Acme Production (111111111111), Admin
+provider "aws" {
+  alias   = "acme_production_admin"
+  profile = "Acme Production_Admin"
+  region  = "eu-west-1"
+}
+
+# 333333333333, OrganizationAccountAccessRole
+provider "aws" {
+  alias   = "acme_production_orgadmin"
+  profile = "Acme Production_OrgAdmin"
+}
+
+# 222222222222, ReadOnly
+provider "aws" {
+  alias   = "legacy"
+  profile = "legacy"
+}
+`},
+		{Steampipe, `# Generated by wasp export from the AWS config file; changes are overwritten.
+
+# Acme Production (111111111111), Admin
+connection "aws_acme_production_admin" {
+  plugin  = "aws"
+  profile = "Acme Production_Admin"
+  regions = ["eu-west-1"]
+}
+
+# 333333333333, OrganizationAccountAccessRole
+connection "aws_acme_production_orgadmin" {
+  plugin  = "aws"
+  profile = "Acme Production_OrgAdmin"
+}
+
+# 222222222222, ReadOnly
+connection "aws_legacy" {
+  plugin  = "aws"
+  profile = "legacy"
+}
+
+# All of the above
+connection "aws" {
+  plugin      = "aws"
+  type        = "aggregator"
+  connections = ["aws_*"]
+}
+`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			cf := loadTestConfig(t)
+			var b strings.Builder
+			if err := Write(&b, tt.format, cf, cf.Profiles.List()); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	cf := loadTestConfig(t)
+	var b strings.Builder
+	if err := Write(&b, JSON, cf, []*awsconfig.Profile{cf.Profiles.Name("Acme Production_Admin")}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "sso_sessions": [
+    {
+      "name": "corp",
+      "start_url": "https://corp.awsapps.com/start",
+      "region": "us-east-1"
+    }
+  ],
+  "profiles": [
+    {
+      "name": "Acme Production_Admin",
+      "sso_session": "corp",
+      "sso_start_url": "https://corp.awsapps.com/start",
+      "sso_region": "us-east-1",
+      "account_id": "111111111111",
+      "account_name": "Acme Production",
+      "role_name": "Admin",
+      "region": "eu-west-1",
+      "managed": true
+    }
+  ]
+}
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	ids := identifiers([]Profile{{Name: "Dev-Admin"}, {Name: "dev admin"}, {Name: "123"}, {Name: "!!"}}, "")
+	want := map[string]string{"Dev-Admin": "dev_admin", "dev admin": "dev_admin_2", "123": "profile_123", "!!": "profile"}
+	for name, id := range want {
+		if ids[name] != id {
+			t.Errorf("identifier for %q = %q, want %q", name, ids[name], id)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("AWS-Vault"); err != nil || f != AWSVault {
+		t.Errorf("ParseFormat(AWS-Vault) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("csv"); err == nil {
+		t.Error("ParseFormat(csv) succeeded")
+	}
+}
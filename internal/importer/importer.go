@@ -0,0 +1,257 @@
+// Package importer reads profiles from other AWS config files, the configs
+// of tools such as aws-vault and Granted, and wasp's own JSON or YAML
+// inventory, and merges them into the AWS config file.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/export"
+	"go.yaml.in/yaml/v3"
+)
+
+// Format is the format of a file to import
+type Format string
+
+const (
+	// AWS is an AWS config file
+	AWS Format = "aws"
+	// AWSVault is an aws-vault config, an AWS config file that usually has
+	// legacy SSO keys
+	AWSVault Format = "aws-vault"
+	// Granted is an AWS config file with the granted_sso_ keys granted sso
+	// generate writes
+	Granted Format = "granted"
+	// JSON and YAML are the inventory wasp export writes
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// Formats lists every supported format
+var Formats = []Format{AWS, AWSVault, Granted, JSON, YAML}
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown import format %q, expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// DetectFormat guesses a file's format from its extension
+func DetectFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	}
+	return AWS
+}
+
+// Set is the profiles and SSO sessions read from a file. Every SSO profile
+// uses one of the sessions; profiles with legacy or granted_sso_ keys get a
+// session made from their start URL.
+type Set struct {
+	SSOSessions []*awsconfig.SSOSession
+	Profiles    []*awsconfig.Profile
+}
+
+// session returns the name of the set's session for a start URL and
+// region, adding one if there isn't one yet
+func (s *Set) session(startURL, region string) string {
+	for _, session := range s.SSOSessions {
+		if session.StartURL == startURL && session.Region == region {
+			return session.Name
+		}
+	}
+	name := sessionName(startURL)
+	unique := name
+	for n := 2; slices.ContainsFunc(s.SSOSessions, func(s *awsconfig.SSOSession) bool { return s.Name == unique }); n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	session := awsconfig.NewSSOSession(unique)
+	session.StartURL, session.Region = startURL, region
+	s.SSOSessions = append(s.SSOSessions, session)
+	return unique
+}
+
+// sessionName names a session after its start URL's subdomain, such as
+// corp for https://corp.awsapps.com/start
+func sessionName(startURL string) string {
+	u, err := url.Parse(startURL)
+	if err != nil || u.Hostname() == "" {
+		return "imported"
+	}
+	name, _, _ := strings.Cut(u.Hostname(), ".")
+	return name
+}
+
+// Read reads the profiles and SSO sessions in a file
+func Read(path string, format Format) (*Set, error) {
+	var set *Set
+	var err error
+	switch format {
+	case AWS, AWSVault, Granted:
+		set, err = readConfig(path)
+	case JSON, YAML:
+		set, err = readInventory(path, format)
+	default:
+		err = fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(set.SSOSessions, func(a, b *awsconfig.SSOSession) int { return strings.Compare(a.Name, b.Name) })
+	slices.SortFunc(set.Profiles, func(a, b *awsconfig.Profile) int { return strings.Compare(a.Name, b.Name) })
+	return set, nil
+}
+
+// mappedKeys are the keys readConfig maps to Profile fields or sessions
+// rather than copying to Settings
+var mappedKeys = []string{
+	"sso_session", "sso_account_id", "sso_role_name", "sso_start_url", "sso_region", "sso_registration_scopes",
+	"role_arn", "source_profile", "credential_source", "services",
+	"granted_sso_start_url", "granted_sso_region", "granted_sso_account_id", "granted_sso_role_name", "common_fate_generated_from",
+}
+
+// readConfig reads an AWS config file. Legacy SSO keys and the keys granted
+// sso generate writes become sso-session references.
+func readConfig(path string) (*Set, error) {
+	cf, err := awsconfig.NewFromConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{}
+	for _, s := range cf.SSOSessions.List() {
+		session := awsconfig.NewSSOSession(s.Name)
+		session.StartURL, session.Region, session.RegistrationScopes = s.StartURL, s.Region, s.RegistrationScopes
+		set.SSOSessions = append(set.SSOSessions, session)
+	}
+	for _, p := range cf.Profiles.List() {
+		keys := cf.ProfileKeys(p.Name)
+		profile := awsconfig.NewProfile(p.Name)
+		profile.SSOSession = p.SSOSession
+		profile.AccountID = first(p.AccountID, keys["granted_sso_account_id"])
+		profile.RoleName = first(p.RoleName, keys["granted_sso_role_name"])
+		profile.RoleARN = p.RoleARN
+		profile.SourceProfile = p.SourceProfile
+		profile.CredentialSource = p.CredentialSource
+		profile.Services = p.Services
+		profile.AccountName = p.AccountName
+		profile.AccountEmail = p.AccountEmail
+		profile.Settings = make(map[string]string)
+
+		startURL := first(keys["sso_start_url"], keys["granted_sso_start_url"])
+		region := first(keys["sso_region"], keys["granted_sso_region"])
+		if profile.SSOSession == "" && startURL != "" {
+			profile.SSOSession = set.session(startURL, region)
+		}
+		for key, value := range keys {
+			if slices.Contains(mappedKeys, key) || strings.HasPrefix(key, "wasp_") {
+				continue
+			}
+			// Granted's credential process is replaced by the SSO keys
+			if key == "credential_process" && strings.HasPrefix(value, "granted credential-process") {
+				continue
+			}
+			profile.Settings[key] = value
+		}
+		set.Profiles = append(set.Profiles, profile)
+	}
+	return set, nil
+}
+
+// readInventory reads the inventory wasp export writes
+func readInventory(path string, format Format) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var inventory export.Inventory
+	if format == JSON {
+		err = json.Unmarshal(data, &inventory)
+	} else {
+		err = yaml.Unmarshal(data, &inventory)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	set := &Set{}
+	for _, s := range inventory.SSOSessions {
+		session := awsconfig.NewSSOSession(s.Name)
+		session.StartURL, session.Region, session.RegistrationScopes = s.StartURL, s.Region, s.RegistrationScopes
+		set.SSOSessions = append(set.SSOSessions, session)
+	}
+	for _, p := range inventory.Profiles {
+		profile := awsconfig.NewProfile(p.Name)
+		profile.AccountName = p.AccountName
+		profile.Settings = make(map[string]string)
+		if p.RoleARN != "" {
+			profile.RoleARN = p.RoleARN
+			profile.SourceProfile = p.SourceProfile
+			profile.CredentialSource = p.CredentialSource
+		} else {
+			profile.AccountID = p.AccountID
+			profile.RoleName = p.RoleName
+		}
+		if p.Region != "" {
+			profile.Settings["region"] = p.Region
+		}
+		known := slices.ContainsFunc(set.SSOSessions, func(s *awsconfig.SSOSession) bool { return s.Name == p.SSOSession })
+		switch {
+		case p.SSOSession != "" && known:
+			profile.SSOSession = p.SSOSession
+		case p.StartURL != "":
+			profile.SSOSession = set.session(p.StartURL, p.SSORegion)
+		}
+		set.Profiles = append(set.Profiles, profile)
+	}
+	return set, nil
+}
+
+// first returns the first value that isn't empty
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// profileKeys returns the keys a profile has, without wasp's own
+func profileKeys(p *awsconfig.Profile) map[string]string {
+	keys := maps.Clone(p.Settings)
+	if keys == nil {
+		keys = make(map[string]string)
+	}
+	for key, value := range map[string]string{
+		"sso_session":       p.SSOSession,
+		"sso_account_id":    p.AccountID,
+		"sso_role_name":     p.RoleName,
+		"role_arn":          p.RoleARN,
+		"source_profile":    p.SourceProfile,
+		"credential_source": p.CredentialSource,
+		"services":          p.Services,
+	} {
+		if value != "" {
+			keys[key] = value
+		}
+	}
+	return keys
+}
@@ -0,0 +1,272 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfig(t *testing.T) {
+	path := writeFile(t, "config", `[profile vault]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 111111111111
+sso_role_name = Admin
+region = eu-west-1
+
+[profile granted]
+granted_sso_start_url = https://corp.awsapps.com/start
+granted_sso_region = us-east-1
+granted_sso_account_id = 222222222222
+granted_sso_role_name = ReadOnly
+common_fate_generated_from = aws-sso
+credential_process = granted credential-process --profile granted
+
+[profile other]
+sso_start_url = https://other.awsapps.com/start
+sso_region = eu-west-1
+sso_account_id = 333333333333
+sso_role_name = Admin
+
+[profile deploy]
+role_arn = arn:aws:iam::444444444444:role/Deploy
+source_profile = vault
+wasp_managed = true
+`)
+	set, err := Read(path, AWSVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions []string
+	for _, s := range set.SSOSessions {
+		sessions = append(sessions, s.Name+" "+s.StartURL+" "+s.Region)
+	}
+	wantSessions := []string{"corp https://corp.awsapps.com/start us-east-1", "other https://other.awsapps.com/start eu-west-1"}
+	if !reflect.DeepEqual(sessions, wantSessions) {
+		t.Errorf("sessions = %q, want %q", sessions, wantSessions)
+	}
+
+	var profiles []map[string]string
+	for _, p := range set.Profiles {
+		keys := profileKeys(p)
+		keys["name"] = p.Name
+		profiles = append(profiles, keys)
+	}
+	want := []map[string]string{
+		{"name": "deploy", "role_arn": "arn:aws:iam::444444444444:role/Deploy", "source_profile": "vault"},
+		{"name": "granted", "sso_session": "corp", "sso_account_id": "222222222222", "sso_role_name": "ReadOnly"},
+		{"name": "other", "sso_session": "other", "sso_account_id": "333333333333", "sso_role_name": "Admin"},
+		{"name": "vault", "sso_session": "corp", "sso_account_id": "111111111111", "sso_role_name": "Admin", "region": "eu-west-1"},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("profiles = %v, want %v", profiles, want)
+	}
+}
+
+func TestReadInventory(t *testing.T) {
+	path := writeFile(t, "inventory.yaml", `sso_sessions:
+  - name: corp
+    start_url: https://corp.awsapps.com/start
+    region: us-east-1
+profiles:
+  - name: admin
+    sso_session: corp
+    account_id: "111111111111"
+    account_name: Acme
+    role_name: Admin
+    region: eu-west-1
+  - name: org
+    account_id: "222222222222"
+    role_name: OrganizationAccountAccessRole
+    role_arn: arn:aws:iam::222222222222:role/OrganizationAccountAccessRole
+    source_profile: admin
+`)
+	set, err := Read(path, DetectFormat(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.SSOSessions) != 1 || set.SSOSessions[0].Name != "corp" {
+		t.Errorf("sessions = %+v, want corp", set.SSOSessions)
+	}
+	admin, org := set.Profiles[0], set.Profiles[1]
+	if admin.SSOSession != "corp" || admin.AccountID != "111111111111" || admin.AccountName != "Acme" || admin.Settings["region"] != "eu-west-1" {
+		t.Errorf("admin = %+v", admin)
+	}
+	// Role profiles don't sign in with SSO themselves
+	if org.AccountID != "" || org.RoleName != "" || org.SourceProfile != "admin" {
+		t.Errorf("org = %+v", org)
+	}
+}
+
+const existingConfig = `[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+
+[profile admin]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+`
+
+func TestMerge(t *testing.T) {
+	importPath := writeFile(t, "import", `[sso-session acme]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+
+[profile admin]
+sso_session = acme
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile readonly]
+sso_session = acme
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+`)
+	set, err := Read(importPath, AWS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := awsconfig.NewFromConfig(writeFile(t, "config", existingConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Merge(cf, set, Options{Strategy: SkipConflicts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The session matches corp by start URL, which makes admin the same
+	if result.Unchanged != 2 || len(result.Conflicts) != 0 || !reflect.DeepEqual(result.Profiles, []string{"readonly"}) {
+		t.Errorf("result = %+v", result)
+	}
+	if p := cf.Profiles.Name("readonly"); p == nil || p.SSOSession != "corp" {
+		t.Errorf("readonly = %+v, want it to use corp", p)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	importPath := writeFile(t, "import", `[profile admin]
+sso_session = corp
+sso_account_id = 222222222222
+sso_role_name = Admin
+
+[profile deploy]
+role_arn = arn:aws:iam::222222222222:role/Deploy
+source_profile = admin
+`)
+	tests := []struct {
+		strategy string
+		profiles []string
+		conflict string
+		source   string
+	}{
+		{SkipConflicts, []string{"deploy"}, "[profile admin] exists with different sso_account_id, skipped", "admin"},
+		{ReplaceConflicts, []string{"admin", "deploy"}, "[profile admin] exists with different sso_account_id, replaced", "admin"},
+		{RenameConflicts, []string{"admin_imported", "deploy"}, `[profile admin] exists with different sso_account_id, imported as "admin_imported"`, "admin_imported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			set, err := Read(importPath, AWS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			configPath := writeFile(t, "config", existingConfig)
+			cf, err := awsconfig.NewFromConfig(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Merge(cf, set, Options{Strategy: tt.strategy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Profiles, tt.profiles) {
+				t.Errorf("profiles = %q, want %q", result.Profiles, tt.profiles)
+			}
+			if len(result.Conflicts) != 1 || result.Conflicts[0].String() != tt.conflict {
+				t.Errorf("conflicts = %v, want %q", result.Conflicts, tt.conflict)
+			}
+			if got := cf.Profiles.Name("deploy").SourceProfile; got != tt.source {
+				t.Errorf("deploy source_profile = %q, want %q", got, tt.source)
+			}
+			if err := cf.Update(); err != nil {
+				t.Fatal(err)
+			}
+			data, _ := os.ReadFile(configPath)
+			if tt.strategy == ReplaceConflicts && !strings.Contains(string(data), "sso_account_id = 222222222222") {
+				t.Errorf("admin wasn't replaced:\n%s", data)
+			}
+
+			// Importing again finds everything already there
+			if result, err = Merge(cf, set, Options{Strategy: tt.strategy}); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Profiles) != 0 {
+				t.Errorf("profiles imported again = %q, want none", result.Profiles)
+			}
+		})
+	}
+
+	set, err := Read(importPath, AWS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := awsconfig.NewFromConfig(writeFile(t, "config", existingConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Merge(cf, set, Options{Strategy: FailOnConflict})
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) || len(conflictErr.Conflicts) != 1 {
+		t.Fatalf("Merge(fail) error = %v, want a ConflictError", err)
+	}
+	if cf.HasProfile("deploy") {
+		t.Error("Merge(fail) imported deploy")
+	}
+}
+
+func TestMergeSessionConflict(t *testing.T) {
+	set, err := Read(writeFile(t, "import", `[sso-session corp]
+sso_start_url = https://other.awsapps.com/start
+sso_region = eu-west-1
+
+[profile other]
+sso_session = corp
+sso_account_id = 333333333333
+sso_role_name = Admin
+`), AWS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := awsconfig.NewFromConfig(writeFile(t, "config", existingConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Merge(cf, set, Options{Strategy: RenameConflicts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.SSOSessions, []string{"corp_imported"}) {
+		t.Errorf("sessions = %q, want corp_imported", result.SSOSessions)
+	}
+	if got := cf.Profiles.Name("other").SSOSession; got != "corp_imported" {
+		t.Errorf("other sso_session = %q, want corp_imported", got)
+	}
+	if got := cf.SSOSessions.Name("corp").StartURL; got != "https://corp.awsapps.com/start" {
+		t.Errorf("corp start URL = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,316 @@
+package importer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+)
+
+// Conflict strategies for sections that are already defined differently
+const (
+	// SkipConflicts keeps the existing section
+	SkipConflicts = "skip"
+	// ReplaceConflicts replaces the existing section with the imported one
+	ReplaceConflicts = "replace"
+	// RenameConflicts imports the section under a new name
+	RenameConflicts = "rename"
+	// FailOnConflict stops the import without changing anything
+	FailOnConflict = "fail"
+)
+
+// Strategies lists every conflict strategy
+var Strategies = []string{SkipConflicts, ReplaceConflicts, RenameConflicts, FailOnConflict}
+
+// Options control how Merge imports a set
+type Options struct {
+	// Strategy is one of Strategies
+	Strategy string
+	// File is the file new sections are written to; empty for the file
+	// the config writes new sections to
+	File string
+}
+
+// Conflict is an imported section that is already defined differently
+type Conflict struct {
+	Type string
+	Name string
+	// Reason says how the sections differ
+	Reason string
+	// Renamed is the name the section was imported under, for the rename
+	// strategy
+	Renamed string
+	// Skipped is set when the section wasn't imported
+	Skipped bool
+}
+
+func (c Conflict) String() string {
+	s := fmt.Sprintf("[%s %s] %s", c.Type, c.Name, c.Reason)
+	switch {
+	case c.Skipped:
+		s += ", skipped"
+	case c.Renamed != "":
+		s += fmt.Sprintf(", imported as %q", c.Renamed)
+	default:
+		s += ", replaced"
+	}
+	return s
+}
+
+// ConflictError reports conflicts when the strategy is to fail
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	lines := []string{"imported sections conflict with the AWS config file; pick another --on-conflict strategy:"}
+	for _, c := range e.Conflicts {
+		lines = append(lines, fmt.Sprintf("  [%s %s] %s", c.Type, c.Name, c.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Result is what Merge imported
+type Result struct {
+	// SSOSessions and Profiles are the names of the sections added or
+	// replaced
+	SSOSessions []string
+	Profiles    []string
+	// Unchanged counts sections that were already defined the same way
+	Unchanged int
+	Conflicts []Conflict
+}
+
+// Merge adds the set's SSO sessions and profiles to cf. Sessions are
+// matched by start URL and region, so profiles use an existing session
+// under whatever name it has. Sections already defined differently are
+// conflicts, resolved by the strategy; with FailOnConflict, cf is left
+// alone and a *ConflictError returned.
+func Merge(cf *awsconfig.ConfigFile, set *Set, opts Options) (*Result, error) {
+	if !slices.Contains(Strategies, opts.Strategy) {
+		return nil, fmt.Errorf("unknown conflict strategy %q, expected one of: %s", opts.Strategy, strings.Join(Strategies, ", "))
+	}
+	if opts.Strategy == FailOnConflict {
+		if conflicts := check(cf, set); len(conflicts) > 0 {
+			return nil, &ConflictError{conflicts}
+		}
+	}
+
+	result := &Result{}
+	sessions := make(map[string]string)
+	for _, s := range set.SSOSessions {
+		if name, ok := matchSession(cf, s); ok {
+			sessions[s.Name] = name
+			result.Unchanged++
+			continue
+		}
+		name := s.Name
+		if cf.HasSSOSession(s.Name) {
+			c := Conflict{Type: "sso-session", Name: s.Name, Reason: sessionReason(cf, s)}
+			switch opts.Strategy {
+			case SkipConflicts:
+				c.Skipped = true
+				result.Conflicts = append(result.Conflicts, c)
+				continue
+			case RenameConflicts:
+				name = unique(s.Name+"_imported", cf.HasSSOSession)
+				c.Renamed = name
+			}
+			result.Conflicts = append(result.Conflicts, c)
+		}
+		session := cf.SSOSession(name)
+		if session.Source == "" {
+			session.Source = opts.File
+		}
+		session.StartURL, session.Region, session.RegistrationScopes = s.StartURL, s.Region, s.RegistrationScopes
+		sessions[s.Name] = name
+		result.SSOSessions = append(result.SSOSessions, name)
+	}
+
+	// Role profiles come after the profiles they assume roles from, so they
+	// can follow them to their new names
+	names := make(map[string]string)
+	for _, p := range dependencyOrder(set.Profiles) {
+		imported := *p
+		if name, ok := names[p.SourceProfile]; ok {
+			imported.SourceProfile = name
+		}
+		if p.SSOSession != "" {
+			name, ok := sessions[p.SSOSession]
+			if !ok && !slices.ContainsFunc(set.SSOSessions, func(s *awsconfig.SSOSession) bool { return s.Name == p.SSOSession }) {
+				// The session isn't part of the set; use it by name
+				name, ok = p.SSOSession, true
+			}
+			if !ok {
+				result.Conflicts = append(result.Conflicts, Conflict{
+					Type: "profile", Name: p.Name, Skipped: true,
+					Reason: fmt.Sprintf("uses sso-session %s, which wasn't imported", p.SSOSession),
+				})
+				continue
+			}
+			imported.SSOSession = name
+		}
+
+		name := p.Name
+		if existing, err := cf.GetProfile(p.Name); err == nil {
+			if maps.Equal(existingKeys(cf, existing.Name), profileKeys(&imported)) {
+				result.Unchanged++
+				continue
+			}
+			c := Conflict{Type: "profile", Name: p.Name, Reason: profileReason(cf, existing, &imported)}
+			switch opts.Strategy {
+			case SkipConflicts:
+				c.Skipped = true
+				result.Conflicts = append(result.Conflicts, c)
+				continue
+			case ReplaceConflicts:
+				cf.DeleteProfile(p.Name)
+			case RenameConflicts:
+				// An earlier import may have renamed the same profile already
+				var same bool
+				name = unique(p.Name+"_imported", func(name string) bool {
+					if cf.HasProfile(name) && maps.Equal(existingKeys(cf, name), profileKeys(&imported)) {
+						same = true
+						return false
+					}
+					return cf.HasProfile(name) || slices.ContainsFunc(set.Profiles, func(p *awsconfig.Profile) bool { return p.Name == name })
+				})
+				if same {
+					names[p.Name] = name
+					result.Unchanged++
+					continue
+				}
+				c.Renamed = name
+			}
+			result.Conflicts = append(result.Conflicts, c)
+		}
+		names[p.Name] = name
+		profile := cf.Profile(name)
+		profile.Source = opts.File
+		profile.NoDefaults = true
+		profile.SSOSession = imported.SSOSession
+		profile.AccountID = imported.AccountID
+		profile.RoleName = imported.RoleName
+		profile.RoleARN = imported.RoleARN
+		profile.SourceProfile = imported.SourceProfile
+		profile.CredentialSource = imported.CredentialSource
+		profile.Services = imported.Services
+		profile.AccountName = imported.AccountName
+		profile.AccountEmail = imported.AccountEmail
+		profile.Settings = maps.Clone(imported.Settings)
+		result.Profiles = append(result.Profiles, name)
+	}
+	slices.Sort(result.Profiles)
+	cf.Profiles.UpdateColWidths()
+	return result, nil
+}
+
+// dependencyOrder sorts profiles by how many source_profile hops within
+// the list they are from credentials, then by name
+func dependencyOrder(profiles []*awsconfig.Profile) []*awsconfig.Profile {
+	byName := make(map[string]*awsconfig.Profile)
+	for _, p := range profiles {
+		byName[p.Name] = p
+	}
+	depth := make(map[string]int)
+	for _, p := range profiles {
+		seen := map[string]bool{p.Name: true}
+		for next := byName[p.SourceProfile]; next != nil && !seen[next.Name]; next = byName[next.SourceProfile] {
+			seen[next.Name] = true
+			depth[p.Name]++
+		}
+	}
+	ret := slices.Clone(profiles)
+	slices.SortStableFunc(ret, func(a, b *awsconfig.Profile) int { return depth[a.Name] - depth[b.Name] })
+	return ret
+}
+
+// check lists the conflicts Merge would find, without changing cf
+func check(cf *awsconfig.ConfigFile, set *Set) []Conflict {
+	var conflicts []Conflict
+	sessions := make(map[string]string)
+	for _, s := range set.SSOSessions {
+		if name, ok := matchSession(cf, s); ok {
+			sessions[s.Name] = name
+			continue
+		}
+		sessions[s.Name] = s.Name
+		if cf.HasSSOSession(s.Name) {
+			conflicts = append(conflicts, Conflict{Type: "sso-session", Name: s.Name, Reason: sessionReason(cf, s)})
+		}
+	}
+	for _, p := range set.Profiles {
+		existing, err := cf.GetProfile(p.Name)
+		if err != nil {
+			continue
+		}
+		imported := *p
+		if name, ok := sessions[p.SSOSession]; ok {
+			imported.SSOSession = name
+		}
+		if !maps.Equal(existingKeys(cf, existing.Name), profileKeys(&imported)) {
+			conflicts = append(conflicts, Conflict{Type: "profile", Name: p.Name, Reason: profileReason(cf, existing, &imported)})
+		}
+	}
+	return conflicts
+}
+
+// matchSession finds the session in cf with the same start URL and region,
+// preferring one with the same name
+func matchSession(cf *awsconfig.ConfigFile, s *awsconfig.SSOSession) (string, bool) {
+	if existing, err := cf.GetSSOSession(s.Name); err == nil && existing.StartURL == s.StartURL && existing.Region == s.Region {
+		return existing.Name, true
+	}
+	var names []string
+	for _, existing := range cf.SSOSessions.List() {
+		if existing.StartURL == s.StartURL && existing.Region == s.Region {
+			names = append(names, existing.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	return slices.Min(names), true
+}
+
+func sessionReason(cf *awsconfig.ConfigFile, s *awsconfig.SSOSession) string {
+	existing, _ := cf.GetSSOSession(s.Name)
+	return fmt.Sprintf("exists with start URL %s in %s, importing %s in %s", existing.StartURL, existing.Region, s.StartURL, s.Region)
+}
+
+// existingKeys returns the keys of a profile in cf, without wasp's own
+func existingKeys(cf *awsconfig.ConfigFile, name string) map[string]string {
+	keys := cf.ProfileKeys(name)
+	maps.DeleteFunc(keys, func(key, _ string) bool { return strings.HasPrefix(key, "wasp_") })
+	return keys
+}
+
+// profileReason names the keys an existing profile and an imported one
+// disagree on
+func profileReason(cf *awsconfig.ConfigFile, existing, imported *awsconfig.Profile) string {
+	old, imp := existingKeys(cf, existing.Name), profileKeys(imported)
+	var differ []string
+	for _, key := range slices.Sorted(maps.Keys(old)) {
+		if value, ok := imp[key]; !ok || value != old[key] {
+			differ = append(differ, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(imp)) {
+		if _, ok := old[key]; !ok {
+			differ = append(differ, key)
+		}
+	}
+	slices.Sort(differ)
+	return "exists with different " + strings.Join(differ, ", ")
+}
+
+// unique returns name, or name with a numeric suffix, whichever isn't taken
+func unique(name string, taken func(string) bool) string {
+	ret := name
+	for n := 2; taken(ret); n++ {
+		ret = fmt.Sprintf("%s_%d", name, n)
+	}
+	return ret
+}
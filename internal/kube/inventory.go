@@ -0,0 +1,109 @@
+// Package kube writes kubeconfig contexts for EKS clusters that get their
+// tokens with the AWS CLI and wasp's profiles.
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"go.yaml.in/yaml/v3"
+)
+
+// Inventory is a cluster inventory file:
+//
+//	clusters:
+//	  - arn: arn:aws:eks:us-east-1:111111111111:cluster/main
+//	    endpoint: https://ABCD.gr7.us-east-1.eks.amazonaws.com
+//	    certificate_authority_data: LS0t...
+type Inventory struct {
+	Clusters []Cluster `yaml:"clusters"`
+}
+
+// Cluster is an EKS cluster. Name, AccountID and Region can be left out
+// when ARN is given, and ARN is filled in from them when it isn't.
+type Cluster struct {
+	Name                     string `yaml:"name,omitempty"`
+	AccountID                string `yaml:"account_id,omitempty"`
+	Region                   string `yaml:"region,omitempty"`
+	ARN                      string `yaml:"arn,omitempty"`
+	Endpoint                 string `yaml:"endpoint"`
+	CertificateAuthorityData string `yaml:"certificate_authority_data,omitempty"`
+}
+
+// ParseClusterARN splits an EKS cluster ARN into its region, account ID and
+// cluster name
+func ParseClusterARN(arn string) (region, accountID, name string, ok bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "eks" {
+		return "", "", "", false
+	}
+	name, ok = strings.CutPrefix(parts[5], "cluster/")
+	if !ok || name == "" || parts[3] == "" || parts[4] == "" {
+		return "", "", "", false
+	}
+	return parts[3], parts[4], name, true
+}
+
+// LoadInventory reads a cluster inventory, rejecting unknown keys and
+// clusters wasp can't write a context for
+func LoadInventory(path string) ([]Cluster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var inv Inventory
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&inv); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	seen := make(map[string]bool)
+	for i := range inv.Clusters {
+		c := &inv.Clusters[i]
+		at := fmt.Sprintf("clusters[%d]", i)
+		if c.ARN != "" {
+			region, accountID, name, ok := ParseClusterARN(c.ARN)
+			if !ok {
+				add("%s.arn: %q isn't an EKS cluster ARN", at, c.ARN)
+				continue
+			}
+			for _, field := range []struct {
+				key        string
+				value, arn string
+				set        *string
+			}{{"name", c.Name, name, &c.Name}, {"account_id", c.AccountID, accountID, &c.AccountID}, {"region", c.Region, region, &c.Region}} {
+				if field.value != "" && field.value != field.arn {
+					add("%s.%s: %q doesn't match the ARN's %q", at, field.key, field.value, field.arn)
+				}
+				*field.set = field.arn
+			}
+		} else {
+			if c.Name == "" || c.AccountID == "" || c.Region == "" {
+				add("%s: set arn, or name, account_id and region", at)
+				continue
+			}
+			c.ARN = fmt.Sprintf("arn:aws:eks:%s:%s:cluster/%s", c.Region, c.AccountID, c.Name)
+		}
+		if c.Endpoint == "" {
+			add("%s.endpoint: required", at)
+		}
+		if seen[c.ARN] {
+			add("%s: cluster %s is listed more than once", at, c.ARN)
+		}
+		seen[c.ARN] = true
+	}
+	if len(problems) > 0 {
+		return nil, &waspconfig.ValidationError{Path: path, Problems: problems}
+	}
+	return inv.Clusters, nil
+}
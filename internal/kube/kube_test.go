@@ -0,0 +1,162 @@
+package kube
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+)
+
+func TestLoadInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.yaml")
+	if err := os.WriteFile(path, []byte(`clusters:
+  - arn: arn:aws:eks:us-east-1:111111111111:cluster/main
+    endpoint: https://main.example.com
+  - name: staging
+    account_id: "222222222222"
+    region: eu-west-1
+    endpoint: https://staging.example.com
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clusters, err := LoadInventory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cluster{
+		{Name: "main", AccountID: "111111111111", Region: "us-east-1", ARN: "arn:aws:eks:us-east-1:111111111111:cluster/main", Endpoint: "https://main.example.com"},
+		{Name: "staging", AccountID: "222222222222", Region: "eu-west-1", ARN: "arn:aws:eks:eu-west-1:222222222222:cluster/staging", Endpoint: "https://staging.example.com"},
+	}
+	if !reflect.DeepEqual(clusters, want) {
+		t.Errorf("clusters = %+v, want %+v", clusters, want)
+	}
+
+	path = filepath.Join(t.TempDir(), "clusters.yaml")
+	if err := os.WriteFile(path, []byte(`clusters:
+  - arn: arn:aws:eks:us-east-1:111111111111:cluster/main
+    region: eu-west-1
+  - name: staging
+    endpoint: https://staging.example.com
+  - arn: arn:aws:iam::111111111111:role/Admin
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadInventory(path)
+	var validationErr *waspconfig.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("LoadInventory() error = %v, want a ValidationError", err)
+	}
+	wantProblems := []string{
+		`clusters[0].region: "eu-west-1" doesn't match the ARN's "us-east-1"`,
+		"clusters[0].endpoint: required",
+		"clusters[1]: set arn, or name, account_id and region",
+		`clusters[2].arn: "arn:aws:iam::111111111111:role/Admin" isn't an EKS cluster ARN`,
+	}
+	if !reflect.DeepEqual(validationErr.Problems, wantProblems) {
+		t.Errorf("problems =\n%s\nwant\n%s", strings.Join(validationErr.Problems, "\n"), strings.Join(wantProblems, "\n"))
+	}
+}
+
+func TestEntries(t *testing.T) {
+	clusters := []Cluster{{Name: "main", AccountID: "111111111111", Region: "us-east-1", ARN: "arn:aws:eks:us-east-1:111111111111:cluster/main"}}
+	profiles := []*awsconfig.Profile{
+		{Name: "prod-admin", SSOSession: "corp", AccountID: "111111111111", RoleName: "Admin"},
+		{Name: "prod-ro", SSOSession: "corp", AccountID: "111111111111", RoleName: "ReadOnly"},
+		{Name: "dev-admin", SSOSession: "corp", AccountID: "222222222222", RoleName: "Admin"},
+	}
+	entries, err := Entries(clusters, profiles, &waspconfig.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Context)
+	}
+	if want := []string{"prod-admin@main", "prod-ro@main"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("contexts = %q, want %q", names, want)
+	}
+
+	// A template that doesn't tell profiles apart is an error
+	wc := &waspconfig.Config{Kube: waspconfig.Kube{Context: "{{.Cluster}}"}}
+	if _, err := Entries(clusters, profiles, wc); err == nil || !strings.Contains(err.Error(), `context "main" is wanted by`) {
+		t.Errorf("Entries() error = %v, want a collision", err)
+	}
+}
+
+const existingKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+  - name: minikube
+    cluster:
+      server: https://127.0.0.1:8443
+      insecure-skip-tls-verify: true
+contexts:
+  - name: minikube
+    context:
+      cluster: minikube
+      user: minikube
+  - name: prod-ro@main
+    context:
+      cluster: minikube
+      user: minikube
+users:
+  - name: minikube
+    user:
+      token: abc
+current-context: minikube
+preferences: {}
+`
+
+func TestSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(existingKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kc, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := Cluster{Name: "main", AccountID: "111111111111", Region: "us-east-1", ARN: "arn:aws:eks:us-east-1:111111111111:cluster/main", Endpoint: "https://main.example.com"}
+	entries := []Entry{
+		{Context: "prod-admin@main", Profile: "prod-admin", Cluster: main},
+		{Context: "prod-ro@main", Profile: "prod-ro", Cluster: main},
+	}
+	changes, skipped := kc.Sync(entries)
+	if !reflect.DeepEqual(skipped, []string{"prod-ro@main"}) {
+		t.Errorf("skipped = %q, want the hand-written prod-ro@main", skipped)
+	}
+	if len(changes) != 2 || !changes[0].New || changes[0].Kind != "cluster" || !changes[1].New || changes[1].Name != "prod-admin@main" {
+		t.Errorf("changes = %+v, want a new cluster and context", changes)
+	}
+	if err := kc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"insecure-skip-tls-verify: true", "preferences: {}", "token: abc", "- --profile\n          - prod-admin", "name: wasp"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("kubeconfig is missing %q:\n%s", want, data)
+		}
+	}
+
+	// Syncing again changes nothing, and a context that's no longer wanted
+	// goes with its user and cluster
+	if kc, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if changes, _ := kc.Sync(entries); len(changes) != 0 {
+		t.Errorf("changes on second sync = %+v, want none", changes)
+	}
+	changes, _ = kc.Sync(nil)
+	if len(changes) != 2 || !changes[0].Removed || !changes[1].Removed {
+		t.Errorf("changes = %+v, want the context and cluster removed", changes)
+	}
+	if len(kc.Contexts) != 2 || len(kc.Users) != 1 || len(kc.Clusters) != 1 {
+		t.Errorf("kubeconfig kept %d contexts, %d users and %d clusters, want 2, 1 and 1", len(kc.Contexts), len(kc.Users), len(kc.Clusters))
+	}
+}
@@ -0,0 +1,147 @@
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Kubeconfig is a kubeconfig file. Keys wasp doesn't know about are kept
+// as they are, so entries written by kubectl or the AWS CLI survive a sync.
+type Kubeconfig struct {
+	APIVersion     string         `yaml:"apiVersion"`
+	Kind           string         `yaml:"kind"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	Users          []NamedUser    `yaml:"users"`
+	CurrentContext string         `yaml:"current-context"`
+	Extra          map[string]any `yaml:",inline"`
+
+	path string
+}
+
+// NamedCluster is an entry in clusters
+type NamedCluster struct {
+	Name    string         `yaml:"name"`
+	Cluster ClusterInfo    `yaml:"cluster"`
+	Extra   map[string]any `yaml:",inline"`
+}
+
+// ClusterInfo is how to reach a cluster
+type ClusterInfo struct {
+	Server                   string         `yaml:"server,omitempty"`
+	CertificateAuthorityData string         `yaml:"certificate-authority-data,omitempty"`
+	Extensions               []Extension    `yaml:"extensions,omitempty"`
+	Extra                    map[string]any `yaml:",inline"`
+}
+
+// NamedContext is an entry in contexts
+type NamedContext struct {
+	Name    string         `yaml:"name"`
+	Context ContextInfo    `yaml:"context"`
+	Extra   map[string]any `yaml:",inline"`
+}
+
+// ContextInfo pairs a cluster with a user
+type ContextInfo struct {
+	Cluster    string         `yaml:"cluster"`
+	User       string         `yaml:"user"`
+	Namespace  string         `yaml:"namespace,omitempty"`
+	Extensions []Extension    `yaml:"extensions,omitempty"`
+	Extra      map[string]any `yaml:",inline"`
+}
+
+// NamedUser is an entry in users
+type NamedUser struct {
+	Name  string         `yaml:"name"`
+	User  UserInfo       `yaml:"user"`
+	Extra map[string]any `yaml:",inline"`
+}
+
+// UserInfo is how to authenticate to a cluster
+type UserInfo struct {
+	Exec       *ExecConfig    `yaml:"exec,omitempty"`
+	Extensions []Extension    `yaml:"extensions,omitempty"`
+	Extra      map[string]any `yaml:",inline"`
+}
+
+// ExecConfig runs a command for a token
+type ExecConfig struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Command    string         `yaml:"command"`
+	Args       []string       `yaml:"args,omitempty"`
+	Extra      map[string]any `yaml:",inline"`
+}
+
+// Extension is a named extension. Entries wasp writes carry one named
+// "wasp" that records the profile they were written for.
+type Extension struct {
+	Name      string         `yaml:"name"`
+	Extension map[string]any `yaml:"extension"`
+}
+
+// extensionName marks the entries wasp writes
+const extensionName = "wasp"
+
+// managed reports whether wasp wrote an entry with these extensions
+func managed(extensions []Extension) bool {
+	for _, e := range extensions {
+		if e.Name == extensionName {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultPath returns the kubeconfig kubectl uses: the first file in
+// KUBECONFIG, or ~/.kube/config
+func DefaultPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// Load reads the kubeconfig at path. A missing file is an empty kubeconfig.
+func Load(path string) (*Kubeconfig, error) {
+	k := &Kubeconfig{APIVersion: "v1", Kind: "Config", path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return k, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Path returns the file the kubeconfig was loaded from
+func (k *Kubeconfig) Path() string {
+	return k.path
+}
+
+// Save writes the kubeconfig back to the file it was loaded from
+func (k *Kubeconfig) Save() error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(k); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(k.path, b.Bytes(), 0o600)
+}
@@ -0,0 +1,185 @@
+package kube
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+)
+
+// Entry is a context to write: a profile getting tokens for a cluster
+type Entry struct {
+	Context string
+	Profile string
+	Cluster Cluster
+}
+
+// Entries pairs every profile with every cluster in its account, naming
+// the contexts with the wasp config's kube.context template. Contexts are
+// sorted by name.
+func Entries(clusters []Cluster, profiles []*awsconfig.Profile, wc *waspconfig.Config) ([]Entry, error) {
+	var entries []Entry
+	byName := make(map[string]Entry)
+	for _, c := range clusters {
+		for _, p := range profiles {
+			if p.AccountID != c.AccountID {
+				continue
+			}
+			name, err := wc.ContextName(waspconfig.KubeData{
+				NameData: waspconfig.NameData{Session: p.SSOSession, AccountID: p.AccountID, AccountName: p.AccountName, AccountEmail: p.AccountEmail, RoleName: p.RoleName},
+				Profile:  p.Name,
+				Cluster:  c.Name,
+				Region:   c.Region,
+			})
+			if err != nil {
+				return nil, err
+			}
+			entry := Entry{Context: name, Profile: p.Name, Cluster: c}
+			if other, ok := byName[name]; ok {
+				return nil, fmt.Errorf("context %q is wanted by %s and %s; change kube.context", name, other, entry)
+			}
+			byName[name] = entry
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Context, b.Context) })
+	return entries, nil
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("profile %q on cluster %s", e.Profile, e.Cluster.ARN)
+}
+
+// Change is a context or cluster that Sync added, changed or removed
+type Change struct {
+	Kind    string // "context" or "cluster"
+	Name    string
+	New     bool
+	Removed bool
+	// Entry is what a context was written for, unset for clusters and
+	// removed contexts
+	Entry Entry
+}
+
+// Sync makes the entries wasp wrote match entries: it writes a context, a
+// user with exec auth and a cluster for each, and removes the contexts,
+// users and clusters it wrote earlier that aren't wanted anymore. Contexts
+// and users wasp didn't write are left alone and returned as skipped, as
+// are clusters, which contexts still use. A context's namespace is kept.
+func (k *Kubeconfig) Sync(entries []Entry) (changes []Change, skipped []string) {
+	wanted := make(map[string]bool)
+	clusters := make(map[string]bool)
+	for _, e := range entries {
+		ci := slices.IndexFunc(k.Contexts, func(c NamedContext) bool { return c.Name == e.Context })
+		ui := slices.IndexFunc(k.Users, func(u NamedUser) bool { return u.Name == e.Context })
+		if ci >= 0 && !managed(k.Contexts[ci].Context.Extensions) || ui >= 0 && !managed(k.Users[ui].User.Extensions) {
+			skipped = append(skipped, e.Context)
+			continue
+		}
+		wanted[e.Context] = true
+		if !clusters[e.Cluster.ARN] {
+			clusters[e.Cluster.ARN] = true
+			if change, ok := k.syncCluster(e.Cluster); ok {
+				changes = append(changes, change)
+			}
+		}
+
+		if ci < 0 {
+			k.Contexts = append(k.Contexts, NamedContext{Name: e.Context})
+			ci = len(k.Contexts) - 1
+		}
+		if ui < 0 {
+			k.Users = append(k.Users, NamedUser{Name: e.Context})
+			ui = len(k.Users) - 1
+		}
+		context, user := &k.Contexts[ci].Context, &k.Users[ui].User
+		before := []any{*context, *user}
+		context.Cluster = e.Cluster.ARN
+		context.User = e.Context
+		context.Extensions = withExtension(context.Extensions, e.Profile)
+		*user = UserInfo{Exec: execConfig(e), Extensions: withExtension(nil, e.Profile)}
+		if !reflect.DeepEqual(before, []any{*context, *user}) {
+			changes = append(changes, Change{Kind: "context", Name: e.Context, New: before[0].(ContextInfo).Cluster == "", Entry: e})
+		}
+	}
+
+	k.Contexts = slices.DeleteFunc(k.Contexts, func(c NamedContext) bool {
+		if !managed(c.Context.Extensions) || wanted[c.Name] {
+			return false
+		}
+		changes = append(changes, Change{Kind: "context", Name: c.Name, Removed: true})
+		if k.CurrentContext == c.Name {
+			k.CurrentContext = ""
+		}
+		return true
+	})
+	k.Users = slices.DeleteFunc(k.Users, func(u NamedUser) bool {
+		return managed(u.User.Extensions) && !wanted[u.Name]
+	})
+	k.Clusters = slices.DeleteFunc(k.Clusters, func(c NamedCluster) bool {
+		if !managed(c.Cluster.Extensions) || clusters[c.Name] || k.clusterInUse(c.Name) {
+			return false
+		}
+		changes = append(changes, Change{Kind: "cluster", Name: c.Name, Removed: true})
+		return true
+	})
+	return changes, skipped
+}
+
+// syncCluster writes a cluster, unless there's one by the same name wasp
+// didn't write
+func (k *Kubeconfig) syncCluster(c Cluster) (Change, bool) {
+	i := slices.IndexFunc(k.Clusters, func(nc NamedCluster) bool { return nc.Name == c.ARN })
+	if i < 0 {
+		k.Clusters = append(k.Clusters, NamedCluster{Name: c.ARN})
+		i = len(k.Clusters) - 1
+	} else if !managed(k.Clusters[i].Cluster.Extensions) {
+		return Change{}, false
+	}
+	cluster := &k.Clusters[i].Cluster
+	before := *cluster
+	cluster.Server = c.Endpoint
+	cluster.CertificateAuthorityData = c.CertificateAuthorityData
+	cluster.Extensions = withExtension(cluster.Extensions, "")
+	if reflect.DeepEqual(before, *cluster) {
+		return Change{}, false
+	}
+	return Change{Kind: "cluster", Name: c.ARN, New: before.Server == ""}, true
+}
+
+// clusterInUse reports whether a context wasp didn't write uses a cluster
+func (k *Kubeconfig) clusterInUse(name string) bool {
+	return slices.ContainsFunc(k.Contexts, func(c NamedContext) bool {
+		return c.Context.Cluster == name && !managed(c.Context.Extensions)
+	})
+}
+
+// withExtension sets the wasp extension, recording the profile an entry
+// was written for
+func withExtension(extensions []Extension, profile string) []Extension {
+	extension := map[string]any{}
+	if profile != "" {
+		extension["profile"] = profile
+	}
+	extensions = slices.DeleteFunc(slices.Clone(extensions), func(e Extension) bool { return e.Name == extensionName })
+	return append(extensions, Extension{Name: extensionName, Extension: extension})
+}
+
+// execConfig gets tokens the way aws eks update-kubeconfig does, with the
+// profile instead of AWS_PROFILE
+func execConfig(e Entry) *ExecConfig {
+	return &ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    "aws",
+		Args: []string{
+			"--region", e.Cluster.Region,
+			"eks", "get-token",
+			"--cluster-name", e.Cluster.Name,
+			"--output", "json",
+			"--profile", e.Profile,
+		},
+	}
+}
@@ -0,0 +1,287 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+)
+
+// section returns where a profile or sso-session is first defined
+func section(cf *awsconfig.ConfigFile, sectionType, name string) awsconfig.RawSection {
+	for _, s := range cf.RawSections() {
+		if s.Type == sectionType && s.Name == name {
+			return s
+		}
+	}
+	return awsconfig.RawSection{Type: sectionType, Name: name, Header: sectionType + " " + name}
+}
+
+// checkSessions reports profiles using SSO sessions that don't exist and
+// sessions missing settings
+func checkSessions(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, p := range cf.Profiles.List() {
+		if p.SSOSession != "" && !cf.HasSSOSession(p.SSOSession) {
+			findings = append(findings, finding(Error, "missing-session", section(cf, "profile", p.Name),
+				"sso_session %q isn't defined", p.SSOSession))
+		}
+	}
+	for _, s := range cf.SSOSessions.List() {
+		for _, key := range []struct{ name, value string }{{"sso_start_url", s.StartURL}, {"sso_region", s.Region}} {
+			if key.value == "" {
+				findings = append(findings, finding(Error, "incomplete-session", section(cf, "sso-session", s.Name),
+					"%s is missing", key.name))
+			}
+		}
+	}
+	return findings
+}
+
+// checkDuplicateSections reports sections defined more than once. Within a
+// file ini merges them, but the AWS CLI refuses to read the file; across
+// the files wasp composes, the first definition wins.
+func checkDuplicateSections(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	byHeader := make(map[string][]awsconfig.RawSection)
+	var headers []string
+	for _, s := range cf.RawSections() {
+		key := s.Type + " " + s.Name
+		if _, ok := byHeader[key]; !ok {
+			headers = append(headers, key)
+		}
+		byHeader[key] = append(byHeader[key], s)
+	}
+	for _, key := range headers {
+		defs := byHeader[key]
+		for i, s := range defs[1:] {
+			first := defs[0]
+			if s.File != first.File {
+				findings = append(findings, finding(Error, "duplicate-section", s,
+					"already defined in %s:%d, which wins", first.File, first.Line))
+				continue
+			}
+			f := finding(Error, "duplicate-section", s, "already defined on line %d; the AWS CLI can't read files with duplicate sections", first.Line)
+			// Merging is safe when the definitions agree on every key they share
+			conflicts := false
+			var shared []awsconfig.RawKey
+			for _, k := range s.Keys {
+				for _, earlier := range defs[:i+1] {
+					for _, prev := range earlier.Values(k.Name) {
+						if earlier.File != s.File {
+							continue
+						}
+						if prev.Value != k.Value {
+							conflicts = true
+						}
+						shared = append(shared, k)
+					}
+				}
+			}
+			if !conflicts {
+				f.Message += "; --fix merges them"
+				f = f.withFix(func(cf *awsconfig.ConfigFile) {
+					cf.Rewrite(s.File)
+					for _, k := range shared {
+						cf.DeleteKey(s, k.Name)
+						cf.SetKey(s, k.Name, k.Value)
+					}
+				})
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// checkDuplicateKeys reports keys set more than once in a section
+func checkDuplicateKeys(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, s := range cf.RawSections() {
+		var seen []string
+		for _, k := range s.Keys {
+			if slices.Contains(seen, k.Name) {
+				continue
+			}
+			seen = append(seen, k.Name)
+			values := s.Values(k.Name)
+			if len(values) < 2 {
+				continue
+			}
+			var lines []string
+			same := true
+			for _, v := range values {
+				lines = append(lines, fmt.Sprint(v.Line))
+				same = same && v.Value == k.Value
+			}
+			f := finding(Error, "duplicate-key", s, "%s is set %d times (lines %s)", k.Name, len(values), strings.Join(lines, ", "))
+			f.Line = values[1].Line
+			if same {
+				f.Message += " to the same value; --fix keeps one"
+				f = f.withFix(func(cf *awsconfig.ConfigFile) {
+					cf.DeleteKey(s, k.Name)
+					cf.SetKey(s, k.Name, k.Value)
+				})
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// checkSourceProfiles reports role profiles that can't get credentials
+func checkSourceProfiles(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, cycle := range cf.Cycles() {
+		err := &awsconfig.CycleError{Profiles: cycle}
+		findings = append(findings, finding(Error, "source-profile-cycle", section(cf, "profile", cycle[0]), "%v", err))
+	}
+	for _, p := range cf.Profiles.List() {
+		s := section(cf, "profile", p.Name)
+		if p.SourceProfile != "" && !cf.HasProfile(p.SourceProfile) {
+			findings = append(findings, finding(Error, "missing-source-profile", s,
+				"source_profile %q isn't defined", p.SourceProfile))
+		}
+		if p.RoleARN == "" {
+			continue
+		}
+		switch {
+		case p.SourceProfile != "" && p.CredentialSource != "":
+			findings = append(findings, finding(Error, "role-credentials", s,
+				"set only one of source_profile and credential_source"))
+		case p.SourceProfile == "" && p.CredentialSource == "" && len(s.Values("web_identity_token_file")) == 0:
+			findings = append(findings, finding(Error, "role-credentials", s,
+				"role_arn needs source_profile, credential_source or web_identity_token_file"))
+		}
+	}
+	return findings
+}
+
+// checkRegions reports region settings that aren't AWS regions
+func checkRegions(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, s := range cf.RawSections() {
+		for _, key := range []string{"region", "sso_region"} {
+			for _, k := range s.Values(key) {
+				if slices.Contains(regions, k.Value) {
+					continue
+				}
+				f := finding(Error, "invalid-region", s, "%s %q isn't an AWS region", key, k.Value)
+				f.Line = k.Line
+				normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(k.Value)), "_", "-")
+				switch {
+				case regionPattern.MatchString(k.Value):
+					f.Severity = Info
+					f.Message = fmt.Sprintf("%s %q isn't a region this wasp knows", key, k.Value)
+				case regionPattern.MatchString(normalized):
+					f.Message += fmt.Sprintf("; --fix changes it to %q", normalized)
+					f = f.withFix(func(cf *awsconfig.ConfigFile) {
+						cf.SetKey(s, key, normalized)
+					})
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// checkUnknownKeys reports keys the AWS CLI doesn't read, which are
+// usually typos
+func checkUnknownKeys(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, s := range cf.RawSections() {
+		var known []string
+		switch s.Type {
+		case "profile":
+			known = profileKeys
+		case "sso-session":
+			known = ssoSessionKeys
+		default:
+			continue
+		}
+		for _, k := range s.Keys {
+			if slices.Contains(known, k.Name) || strings.HasPrefix(k.Name, "wasp_") {
+				continue
+			}
+			f := finding(Warning, "unknown-key", s, "unknown key %s", k.Name)
+			f.Line = k.Line
+			if suggestion, ok := closest(k.Name, known); ok {
+				f.Message += fmt.Sprintf("; did you mean %s?", suggestion)
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// checkLegacySSO reports profiles configured with the SSO settings that
+// predate sso-session sections
+func checkLegacySSO(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, p := range cf.Profiles.List() {
+		s := section(cf, "profile", p.Name)
+		startURL, region := s.Values("sso_start_url"), s.Values("sso_region")
+		if len(startURL) == 0 && len(region) == 0 {
+			continue
+		}
+		if p.SSOSession == "" {
+			findings = append(findings, finding(Info, "legacy-sso", s,
+				"uses legacy SSO settings; tokens can't be refreshed without an sso-session section"))
+			continue
+		}
+		f := finding(Warning, "mixed-sso", s, "sets sso_session and the legacy sso_start_url or sso_region")
+		session, err := cf.GetSSOSession(p.SSOSession)
+		if err != nil {
+			findings = append(findings, f)
+			continue
+		}
+		// The legacy keys are only redundant if they agree with the session
+		agrees := true
+		for _, k := range startURL {
+			agrees = agrees && k.Value == session.StartURL
+		}
+		for _, k := range region {
+			agrees = agrees && k.Value == session.Region
+		}
+		if agrees {
+			f.Message += "; --fix removes the legacy keys"
+			f = f.withFix(func(cf *awsconfig.ConfigFile) {
+				cf.DeleteKey(s, "sso_start_url")
+				cf.DeleteKey(s, "sso_region")
+			})
+		} else {
+			f.Severity = Error
+			f.Message = fmt.Sprintf("sets sso_start_url or sso_region differently from sso-session %s", session.Name)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// checkTokens reports SSO sessions whose cached tokens have expired
+func checkTokens(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	if opts.Token == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, session := range cf.SSOSessions.List() {
+		s := section(cf, "sso-session", session.Name)
+		token, err := opts.Token(session.Name)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			findings = append(findings, finding(Info, "expired-token", s, "not signed in"))
+		case err != nil:
+			findings = append(findings, finding(Warning, "expired-token", s, "can't read the cached token: %v", err))
+		case !token.Expired(opts.Now):
+		case token.CanRefresh(opts.Now):
+			findings = append(findings, finding(Info, "expired-token", s, "token expired at %s; it's refreshed on next use", token.ExpiresAt.Local().Format("2006-01-02 15:04")))
+		default:
+			findings = append(findings, finding(Warning, "expired-token", s, "token expired at %s and can't be refreshed; sign in again", token.ExpiresAt.Local().Format("2006-01-02 15:04")))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,131 @@
+package lint
+
+import (
+	"regexp"
+	"slices"
+)
+
+// profileKeys are the settings the AWS CLI and SDKs read from a profile
+var profileKeys = []string{
+	"account_id_endpoint_mode",
+	"auth_scheme_preference",
+	"aws_access_key_id",
+	"aws_account_id",
+	"aws_secret_access_key",
+	"aws_session_token",
+	"ca_bundle",
+	"cli_auto_prompt",
+	"cli_binary_format",
+	"cli_follow_urlparam",
+	"cli_history",
+	"cli_pager",
+	"cli_timestamp_format",
+	"credential_process",
+	"credential_source",
+	"defaults_mode",
+	"disable_request_compression",
+	"duration_seconds",
+	"ec2_metadata_service_endpoint",
+	"ec2_metadata_service_endpoint_mode",
+	"ec2_metadata_v1_disabled",
+	"endpoint_discovery_enabled",
+	"endpoint_url",
+	"external_id",
+	"ignore_configured_endpoint_urls",
+	"max_attempts",
+	"metadata_service_num_attempts",
+	"metadata_service_timeout",
+	"mfa_serial",
+	"output",
+	"parameter_validation",
+	"region",
+	"request_checksum_calculation",
+	"request_min_compression_size_bytes",
+	"response_checksum_validation",
+	"retry_mode",
+	"role_arn",
+	"role_session_name",
+	"s3",
+	"sdk_ua_app_id",
+	"services",
+	"sigv4a_signing_region_set",
+	"source_profile",
+	"sso_account_id",
+	"sso_region",
+	"sso_registration_scopes",
+	"sso_role_name",
+	"sso_session",
+	"sso_start_url",
+	"sts_regional_endpoints",
+	"tcp_keepalive",
+	"use_dualstack_endpoint",
+	"use_fips_endpoint",
+	"web_identity_token_file",
+}
+
+// ssoSessionKeys are the settings of an sso-session section
+var ssoSessionKeys = []string{
+	"sso_region",
+	"sso_registration_scopes",
+	"sso_start_url",
+}
+
+// regionPattern is the shape of every AWS region name
+var regionPattern = regexp.MustCompile(`^[a-z]{2,4}(-[a-z]+)+-[0-9]+$`)
+
+// regions are the AWS regions wasp knows about. New regions are only
+// reported as unknown, not as mistakes.
+var regions = []string{
+	"af-south-1",
+	"ap-east-1", "ap-east-2",
+	"ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+	"ap-south-1", "ap-south-2",
+	"ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4", "ap-southeast-5", "ap-southeast-6", "ap-southeast-7",
+	"ca-central-1", "ca-west-1",
+	"cn-north-1", "cn-northwest-1",
+	"eu-central-1", "eu-central-2",
+	"eu-north-1",
+	"eu-south-1", "eu-south-2",
+	"eu-west-1", "eu-west-2", "eu-west-3",
+	"eusc-de-east-1",
+	"il-central-1",
+	"me-central-1", "me-south-1",
+	"mx-central-1",
+	"sa-east-1",
+	"us-east-1", "us-east-2",
+	"us-gov-east-1", "us-gov-west-1",
+	"us-west-1", "us-west-2",
+}
+
+// closest returns the known key nearest to key, if it's close enough to be
+// a typo
+func closest(key string, known []string) (string, bool) {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := distance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best, best != ""
+}
+
+// distance is the Levenshtein distance between two strings
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = slices.Min([]int{prev[j] + 1, cur[j-1] + 1, prev[j-1] + cost})
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,145 @@
+// Package lint checks AWS config files for mistakes the AWS CLI and SDKs
+// would trip over, and fixes the ones that can be fixed safely.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+)
+
+// Severity is how much a finding matters
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// MarshalText writes the severity by name, for JSON and YAML output
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses a severity name
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected one of: %s", name, strings.Join(severityNames, ", "))
+}
+
+// Finding is a problem in a config file
+type Finding struct {
+	Severity Severity `json:"severity" yaml:"severity"`
+	Check    string   `json:"check" yaml:"check"`
+	File     string   `json:"file,omitempty" yaml:"file,omitempty"`
+	Line     int      `json:"line,omitempty" yaml:"line,omitempty"`
+	Section  string   `json:"section,omitempty" yaml:"section,omitempty"`
+	Message  string   `json:"message" yaml:"message"`
+	Fixable  bool     `json:"fixable" yaml:"fixable"`
+
+	fix func(cf *awsconfig.ConfigFile)
+}
+
+// Location returns where the finding is, as file:line
+func (f Finding) Location() string {
+	if f.Line == 0 {
+		return f.File
+	}
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+// Options control the checks that look beyond the config files
+type Options struct {
+	// Now is when cached tokens are checked for expiry
+	Now time.Time
+
+	// Token loads the cached token for an SSO session. Tokens aren't
+	// checked without it.
+	Token func(session string) (*ssocache.Token, error)
+}
+
+type check func(cf *awsconfig.ConfigFile, opts Options) []Finding
+
+var checks = []check{
+	checkSessions,
+	checkDuplicateSections,
+	checkDuplicateKeys,
+	checkSourceProfiles,
+	checkRegions,
+	checkUnknownKeys,
+	checkLegacySSO,
+	checkTokens,
+}
+
+// Run checks the config and returns what it found, in file order
+func Run(cf *awsconfig.ConfigFile, opts Options) []Finding {
+	var findings []Finding
+	for _, c := range checks {
+		findings = append(findings, c(cf, opts)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// Fix applies the safe fixes for the findings, returning how many it
+// applied. The changes are written by the config's Update.
+func Fix(cf *awsconfig.ConfigFile, findings []Finding) int {
+	fixed := 0
+	for _, f := range findings {
+		if f.fix != nil {
+			f.fix(cf)
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// Count returns how many findings are at least as severe as min
+func Count(findings []Finding, min Severity) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity >= min {
+			n++
+		}
+	}
+	return n
+}
+
+// finding starts a finding about a raw section
+func finding(severity Severity, check string, s awsconfig.RawSection, format string, args ...any) Finding {
+	return Finding{
+		Severity: severity,
+		Check:    check,
+		File:     s.File,
+		Line:     s.Line,
+		Section:  s.Header,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// withFix makes a finding fixable
+func (f Finding) withFix(fix func(cf *awsconfig.ConfigFile)) Finding {
+	f.Fixable = true
+	f.fix = fix
+	return f
+}
@@ -0,0 +1,152 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/ssocache"
+)
+
+const problems = `[default]
+region = us-east-1
+
+[sso-session corp]
+sso_start_url = https://example.awsapps.com/start
+sso_region = us-east-1
+
+[sso-session partner]
+sso_start_url = https://partner.awsapps.com/start
+
+[profile dev]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+sso_start_url = https://example.awsapps.com/start
+region = US_EAST_1
+regoin = us-east-1
+
+[profile prod]
+sso_session = missing
+sso_account_id = 222222222222
+sso_role_name = Admin
+output = json
+output = json
+
+[profile a]
+role_arn = arn:aws:iam::111111111111:role/A
+source_profile = b
+
+[profile b]
+role_arn = arn:aws:iam::111111111111:role/B
+source_profile = a
+
+[profile orphan]
+role_arn = arn:aws:iam::111111111111:role/C
+
+[profile legacy]
+sso_start_url = https://example.awsapps.com/start
+sso_region = mars-north-1
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile dev]
+output = table
+`
+
+func load(t *testing.T, path string) *awsconfig.ConfigFile {
+	t.Helper()
+	cf, err := awsconfig.NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cf
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(problems), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	opts := Options{
+		Now: now,
+		Token: func(session string) (*ssocache.Token, error) {
+			if session == "partner" {
+				return nil, os.ErrNotExist
+			}
+			return &ssocache.Token{AccessToken: "x", ExpiresAt: now.Add(-time.Hour)}, nil
+		},
+	}
+
+	type result struct {
+		check    string
+		line     int
+		severity Severity
+		fixable  bool
+	}
+	want := []result{
+		{"expired-token", 4, Warning, false},
+		{"incomplete-session", 8, Error, false},
+		{"expired-token", 8, Info, false},
+		{"mixed-sso", 11, Warning, true},
+		{"invalid-region", 16, Error, true},
+		{"unknown-key", 17, Warning, false},
+		{"missing-session", 19, Error, false},
+		{"duplicate-key", 24, Error, true},
+		{"source-profile-cycle", 26, Error, false},
+		{"role-credentials", 34, Error, false},
+		{"legacy-sso", 37, Info, false},
+		{"invalid-region", 39, Info, false},
+		{"duplicate-section", 43, Error, true},
+	}
+	findings := Run(load(t, path), opts)
+	var got []result
+	for _, f := range findings {
+		got = append(got, result{f.Check, f.Line, f.Severity, f.Fixable})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Run() =\n%v\nwant\n%v", got, want)
+		for _, f := range findings {
+			t.Log(f.Location(), f.Check, f.Message)
+		}
+	}
+
+	cf := load(t, path)
+	if n := Fix(cf, Run(cf, opts)); n != 4 {
+		t.Errorf("Fix() = %d, want 4", n)
+	}
+	if err := cf.Update(); err != nil {
+		t.Fatal(err)
+	}
+	cf = load(t, path)
+	for _, f := range Run(cf, opts) {
+		if f.Fixable {
+			t.Errorf("%s still reported after --fix: %s", f.Check, f.Message)
+		}
+	}
+	dev := cf.Profile("dev")
+	if dev.Settings != nil {
+		t.Errorf("dev settings = %v", dev.Settings)
+	}
+	raw := cf.RawSections()
+	i := slices.IndexFunc(raw, func(s awsconfig.RawSection) bool { return s.Header == "profile dev" })
+	for key, want := range map[string]string{"region": "us-east-1", "output": "table", "sso_start_url": ""} {
+		values := raw[i].Values(key)
+		if want == "" && len(values) != 0 || want != "" && (len(values) != 1 || values[0].Value != want) {
+			t.Errorf("dev %s = %v, want %q", key, values, want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if d := distance("regoin", "region"); d != 2 {
+		t.Errorf("distance = %d, want 2", d)
+	}
+	if key, ok := closest("ouptut", profileKeys); !ok || key != "output" {
+		t.Errorf("closest = %q, %v", key, ok)
+	}
+}
@@ -0,0 +1,156 @@
+package manifest
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+)
+
+// ownKeys are the profile keys a manifest profile sets with its own fields
+var ownKeys = []string{"sso_session", "sso_account_id", "sso_role_name", "sso_start_url", "sso_region", "role_arn", "source_profile", "credential_source"}
+
+// Generate builds a manifest from the AWS config file and the wasp config:
+// every SSO session with the account roles its profiles sign in to, and the
+// wasp config's naming, defaults, rules, chains and aliases. Profiles the
+// naming template or the chains wouldn't name the way they're named are
+// listed in Profiles, as are other role profiles. Alias profiles and
+// profiles with credentials of their own are left out.
+func Generate(name string, cf *awsconfig.ConfigFile, wc *waspconfig.Config) (*Manifest, error) {
+	m := &Manifest{
+		Version:  Version,
+		Name:     name,
+		Naming:   wc.Naming,
+		Defaults: wc.Defaults,
+		Rules:    wc.Rules,
+		Chains:   wc.Chains,
+		Aliases:  wc.Aliases,
+	}
+
+	profiles := cf.Profiles.List()
+	slices.SortFunc(profiles, func(a, b *awsconfig.Profile) int { return strings.Compare(a.Name, b.Name) })
+
+	type accountKey struct{ session, id string }
+	accounts := make(map[accountKey]*Account)
+	targets := make(map[waspconfig.Target]string)
+	var sso []*awsconfig.Profile
+	var roles []waspconfig.NameData
+	for _, p := range profiles {
+		if p.AliasFor != "" || p.RoleARN != "" || p.SSOSession == "" || p.AccountID == "" || p.RoleName == "" || !cf.HasSSOSession(p.SSOSession) {
+			continue
+		}
+		key := accountKey{p.SSOSession, p.AccountID}
+		account, ok := accounts[key]
+		if !ok {
+			account = &Account{ID: p.AccountID}
+			accounts[key] = account
+		}
+		account.Name = first(account.Name, p.AccountName)
+		account.Email = first(account.Email, p.AccountEmail)
+		sso = append(sso, p)
+		target := waspconfig.Target{Session: p.SSOSession, AccountID: p.AccountID, RoleName: p.RoleName}
+		if _, ok := targets[target]; !ok {
+			targets[target] = ""
+			roles = append(roles, waspconfig.NameData{Session: p.SSOSession, AccountID: p.AccountID, RoleName: p.RoleName})
+		}
+	}
+
+	// An account role is listed in its account when apply would give its
+	// profile the name it has now, collisions included
+	for i := range roles {
+		account := accounts[accountKey{roles[i].Session, roles[i].AccountID}]
+		roles[i].AccountName, roles[i].AccountEmail = account.Name, account.Email
+	}
+	resolved, _, err := wc.ResolveNames(roles, func(name string) (waspconfig.Target, bool) {
+		p, err := cf.GetProfile(name)
+		if err != nil {
+			return waspconfig.Target{}, false
+		}
+		return waspconfig.Target{Session: p.SSOSession, AccountID: p.AccountID, RoleName: p.RoleName}, true
+	})
+	// With collisions set to fail, colliding roles keep their names by
+	// being listed in Profiles
+	var collisionErr *waspconfig.CollisionError
+	if err != nil && !errors.As(err, &collisionErr) {
+		return nil, err
+	}
+	for _, r := range resolved {
+		targets[waspconfig.Target{Session: r.Data.Session, AccountID: r.Data.AccountID, RoleName: r.Data.RoleName}] = r.Name
+	}
+
+	var planned []waspconfig.Planned
+	for _, p := range sso {
+		account := accounts[accountKey{p.SSOSession, p.AccountID}]
+		data := waspconfig.NameData{Session: p.SSOSession, AccountID: p.AccountID, AccountName: account.Name, AccountEmail: account.Email, RoleName: p.RoleName}
+		if session, err := cf.GetSSOSession(p.SSOSession); err == nil {
+			data.SSORegion = session.Region
+		}
+		planned = append(planned, waspconfig.Planned{Name: p.Name, Data: data})
+		if targets[waspconfig.Target{Session: p.SSOSession, AccountID: p.AccountID, RoleName: p.RoleName}] == p.Name {
+			account.Roles = append(account.Roles, p.RoleName)
+			continue
+		}
+		m.Profiles = append(m.Profiles, Profile{Name: p.Name, Session: p.SSOSession, AccountID: p.AccountID, Role: p.RoleName})
+	}
+
+	chained, err := wc.Chained(planned)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.AliasFor != "" || p.RoleARN == "" {
+			continue
+		}
+		i := slices.IndexFunc(chained, func(ch waspconfig.ChainedProfile) bool { return ch.Name == p.Name })
+		if i >= 0 && chained[i].RoleARN == p.RoleARN && chained[i].SourceProfile == p.SourceProfile {
+			continue
+		}
+		m.Profiles = append(m.Profiles, Profile{Name: p.Name, RoleARN: p.RoleARN, SourceProfile: p.SourceProfile, CredentialSource: p.CredentialSource})
+	}
+
+	for _, s := range cf.SSOSessions.List() {
+		session := Session{Name: s.Name, StartURL: s.StartURL, Region: s.Region, RegistrationScopes: s.RegistrationScopes}
+		for key, account := range accounts {
+			if key.session == s.Name {
+				slices.Sort(account.Roles)
+				session.Accounts = append(session.Accounts, *account)
+			}
+		}
+		slices.SortFunc(session.Accounts, func(a, b Account) int { return strings.Compare(a.ID, b.ID) })
+		m.Sessions = append(m.Sessions, session)
+	}
+	slices.SortFunc(m.Sessions, func(a, b Session) int { return strings.Compare(a.Name, b.Name) })
+
+	// Profiles given by name keep the keys the defaults and rules wouldn't
+	// give them; profiles named by the template are left to those
+	for i, mp := range m.Profiles {
+		want := make(map[string]string)
+		for _, s := range m.Settings(mp) {
+			want[s.Key] = s.Value
+		}
+		keys := cf.ProfileKeys(mp.Name)
+		for _, key := range slices.Sorted(maps.Keys(keys)) {
+			if slices.Contains(ownKeys, key) || strings.HasPrefix(key, "wasp_") || want[key] == keys[key] {
+				continue
+			}
+			if m.Profiles[i].Set == nil {
+				m.Profiles[i].Set = make(map[string]string)
+			}
+			m.Profiles[i].Set[key] = keys[key]
+		}
+	}
+	return m, nil
+}
+
+// first returns the first value that isn't empty
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,302 @@
+// Package manifest reads team manifests: declarative lists of the SSO
+// sessions, accounts, roles, naming rules and aliases a team's AWS config
+// should have, kept in git and applied with wasp apply.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+	"go.yaml.in/yaml/v3"
+)
+
+// Version is the manifest version this wasp reads and writes
+const Version = 1
+
+// Manifest is a team manifest. Profiles for every role of every account
+// are named and filled in the same way wasp sync does it, with the naming,
+// defaults, rules and chains of the manifest rather than the wasp config.
+type Manifest struct {
+	Version int `yaml:"version"`
+
+	// Name identifies the manifest; the profiles it creates are marked
+	// with it so apply can remove them once they leave the manifest
+	Name string `yaml:"name"`
+
+	Sessions []Session          `yaml:"sessions,omitempty"`
+	Naming   waspconfig.Naming  `yaml:"naming,omitempty"`
+	Defaults map[string]string  `yaml:"defaults,omitempty"`
+	Rules    []waspconfig.Rule  `yaml:"rules,omitempty"`
+	Chains   []waspconfig.Chain `yaml:"chains,omitempty"`
+
+	// Profiles are profiles that aren't named by the naming template, or
+	// don't sign in with SSO
+	Profiles []Profile `yaml:"profiles,omitempty"`
+
+	// Aliases are added to the wasp config
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+
+	path string
+}
+
+// Session is an SSO session and the account roles to create profiles for
+type Session struct {
+	Name               string    `yaml:"name"`
+	StartURL           string    `yaml:"start_url"`
+	Region             string    `yaml:"region"`
+	RegistrationScopes []string  `yaml:"registration_scopes,omitempty"`
+	Accounts           []Account `yaml:"accounts,omitempty"`
+}
+
+// Account is an AWS account and the roles in it to create profiles for.
+// An account without roles only names the account for Profiles in it.
+type Account struct {
+	ID    string   `yaml:"id"`
+	Name  string   `yaml:"name,omitempty"`
+	Email string   `yaml:"email,omitempty"`
+	Roles []string `yaml:"roles,omitempty"`
+}
+
+// Profile is a profile given by name: an SSO profile with Session,
+// AccountID and Role, or one that assumes RoleARN from SourceProfile or
+// CredentialSource. Set adds keys on top of the defaults and rules.
+type Profile struct {
+	Name             string            `yaml:"name"`
+	Session          string            `yaml:"session,omitempty"`
+	AccountID        string            `yaml:"account_id,omitempty"`
+	Role             string            `yaml:"role,omitempty"`
+	RoleARN          string            `yaml:"role_arn,omitempty"`
+	SourceProfile    string            `yaml:"source_profile,omitempty"`
+	CredentialSource string            `yaml:"credential_source,omitempty"`
+	Set              map[string]string `yaml:"set,omitempty"`
+}
+
+// SSO reports whether the profile signs in with AWS SSO
+func (p Profile) SSO() bool {
+	return p.RoleARN == ""
+}
+
+// Data returns what naming templates and rules see for an SSO profile
+func (p Profile) Data(m *Manifest) waspconfig.NameData {
+	data := waspconfig.NameData{Session: p.Session, AccountID: p.AccountID, RoleName: p.Role}
+	if account, ok := m.account(p.Session, p.AccountID); ok {
+		data.AccountName, data.AccountEmail = account.Name, account.Email
+	}
+	for _, s := range m.Sessions {
+		if s.Name == p.Session {
+			data.SSORegion = s.Region
+		}
+	}
+	return data
+}
+
+// Settings returns the keys to write into a profile given by name: the
+// defaults and matching rules, then the profile's Set
+func (m *Manifest) Settings(p Profile) []waspconfig.Setting {
+	var data waspconfig.NameData
+	if p.SSO() {
+		data = p.Data(m)
+	}
+	settings := m.Config().Settings(data)
+	for _, key := range slices.Sorted(maps.Keys(p.Set)) {
+		i := slices.IndexFunc(settings, func(s waspconfig.Setting) bool { return s.Key == key })
+		setting := waspconfig.Setting{Key: key, Value: p.Set[key], Source: "profiles." + p.Name}
+		if i >= 0 {
+			settings[i] = setting
+		} else {
+			settings = append(settings, setting)
+		}
+	}
+	slices.SortFunc(settings, func(a, b waspconfig.Setting) int { return strings.Compare(a.Key, b.Key) })
+	return settings
+}
+
+// Load reads a manifest, rejecting unknown keys
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{path: path}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Path returns the file the manifest was loaded from
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Write writes the manifest as YAML
+func (m *Manifest) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Config returns a wasp config with the manifest's naming, defaults, rules
+// and chains, which name and fill in its profiles
+func (m *Manifest) Config() *waspconfig.Config {
+	return &waspconfig.Config{
+		Version:  waspconfig.Version,
+		Naming:   m.Naming,
+		Defaults: m.Defaults,
+		Rules:    m.Rules,
+		Chains:   m.Chains,
+	}
+}
+
+// Roles returns every account role of every session
+func (m *Manifest) Roles() []waspconfig.NameData {
+	var roles []waspconfig.NameData
+	for _, s := range m.Sessions {
+		for _, a := range s.Accounts {
+			for _, role := range a.Roles {
+				roles = append(roles, waspconfig.NameData{
+					Session:      s.Name,
+					SSORegion:    s.Region,
+					AccountID:    a.ID,
+					AccountName:  a.Name,
+					AccountEmail: a.Email,
+					RoleName:     role,
+				})
+			}
+		}
+	}
+	return roles
+}
+
+func (m *Manifest) account(session, id string) (Account, bool) {
+	for _, s := range m.Sessions {
+		if s.Name != session {
+			continue
+		}
+		for _, a := range s.Accounts {
+			if a.ID == id {
+				return a, true
+			}
+		}
+	}
+	return Account{}, false
+}
+
+// Validate checks the manifest for values wasp can't use, returning a
+// *waspconfig.ValidationError describing all of them
+func (m *Manifest) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if m.Version != Version {
+		add("version: must be %d", Version)
+	}
+	if m.Name == "" {
+		add("name: required")
+	} else if strings.ContainsAny(m.Name, "\n") {
+		add("name: can't contain newlines")
+	}
+
+	sessions := make(map[string]bool)
+	for i, s := range m.Sessions {
+		at := fmt.Sprintf("sessions[%d]", i)
+		if s.Name == "" {
+			add("%s.name: required", at)
+		} else if sessions[s.Name] {
+			add("%s.name: session %q is listed more than once", at, s.Name)
+		}
+		sessions[s.Name] = true
+		if s.StartURL == "" {
+			add("%s.start_url: required", at)
+		}
+		if s.Region == "" {
+			add("%s.region: required", at)
+		}
+		accounts := make(map[string]bool)
+		for j, a := range s.Accounts {
+			accountAt := fmt.Sprintf("%s.accounts[%d]", at, j)
+			if a.ID == "" {
+				add("%s.id: required", accountAt)
+			} else if accounts[a.ID] {
+				add("%s.id: account %s is listed more than once", accountAt, a.ID)
+			}
+			accounts[a.ID] = true
+			for k, role := range a.Roles {
+				if role == "" {
+					add("%s.roles[%d]: empty entry", accountAt, k)
+				}
+			}
+		}
+	}
+
+	// Naming, defaults, rules and chains mean what they mean in the wasp
+	// config
+	var configErr *waspconfig.ValidationError
+	if err := m.Config().Validate(); errors.As(err, &configErr) {
+		problems = append(problems, configErr.Problems...)
+	} else if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for i, p := range m.Profiles {
+		at := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case p.Name == "":
+			add("%s.name: required", at)
+		case names[p.Name]:
+			add("%s.name: profile %q is listed more than once", at, p.Name)
+		case strings.ContainsAny(p.Name, "[]\n"):
+			add("%s.name: profile names can't contain brackets or newlines", at)
+		}
+		names[p.Name] = true
+		if p.SSO() {
+			if p.Session == "" || p.AccountID == "" || p.Role == "" {
+				add("%s: set session, account_id and role, or role_arn", at)
+			} else if !sessions[p.Session] {
+				add("%s.session: %q isn't one of the manifest's sessions", at, p.Session)
+			}
+		} else {
+			if p.Session != "" || p.AccountID != "" || p.Role != "" {
+				add("%s: role_arn can't be combined with session, account_id or role", at)
+			}
+			if (p.SourceProfile == "") == (p.CredentialSource == "") {
+				add("%s: set one of source_profile or credential_source with role_arn", at)
+			}
+			if _, _, ok := awsconfig.ParseRoleARN(p.RoleARN); !ok {
+				add("%s.role_arn: %q isn't an IAM role ARN", at, p.RoleARN)
+			}
+		}
+		for key := range p.Set {
+			if strings.HasPrefix(key, "sso_") || strings.HasPrefix(key, "wasp_") || key == "role_arn" || key == "source_profile" || key == "credential_source" {
+				add("%s.set.%s: set it with the profile's own fields", at, key)
+			}
+		}
+	}
+
+	for alias, target := range m.Aliases {
+		if target == "" {
+			add("aliases.%s: missing profile name", alias)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &waspconfig.ValidationError{Path: m.path, Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+	"github.com/buzzsurfr/wasp/internal/waspconfig"
+)
+
+const teamManifest = `version: 1
+name: platform
+sessions:
+  - name: corp
+    start_url: https://corp.awsapps.com/start
+    region: us-east-1
+    accounts:
+      - id: "111111111111"
+        name: Acme Production
+        roles: [Admin, ReadOnly]
+naming:
+  template: "{{.AccountName}}_{{.RoleName}}"
+defaults:
+  region: us-east-1
+rules:
+  - role: ReadOnly
+    set:
+      output: table
+profiles:
+  - name: prod-ro
+    session: corp
+    account_id: "111111111111"
+    role: ReadOnly
+    set:
+      region: eu-west-1
+  - name: ci
+    role_arn: arn:aws:iam::111111111111:role/CI
+    credential_source: Ec2InstanceMetadata
+aliases:
+  prod: Acme Production_Admin
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte(teamManifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, role := range m.Roles() {
+		names = append(names, role.AccountName+"/"+role.RoleName)
+	}
+	if want := []string{"Acme Production/Admin", "Acme Production/ReadOnly"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Roles() = %q, want %q", names, want)
+	}
+
+	// Set beats the rules, which beat the defaults
+	settings := make(map[string]string)
+	for _, s := range m.Settings(m.Profiles[0]) {
+		settings[s.Key] = s.Value + " " + s.Source
+	}
+	want := map[string]string{"region": "eu-west-1 profiles.prod-ro", "output": "table rules[0]"}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Settings(prod-ro) = %v, want %v", settings, want)
+	}
+
+	path = filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte(teamManifest+"extra: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load accepted an unknown key")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte(`version: 1
+sessions:
+  - name: corp
+    accounts:
+      - id: "111111111111"
+      - id: "111111111111"
+profiles:
+  - name: a
+    session: other
+    account_id: "111111111111"
+    role: Admin
+  - name: b
+    role_arn: not-an-arn
+    set:
+      sso_role_name: Admin
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *waspconfig.ValidationError
+	if err := m.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() = %v, want a ValidationError", err)
+	}
+	want := []string{
+		"name: required",
+		"sessions[0].start_url: required",
+		"sessions[0].region: required",
+		"sessions[0].accounts[1].id: account 111111111111 is listed more than once",
+		`profiles[0].session: "other" isn't one of the manifest's sessions`,
+		"profiles[1]: set one of source_profile or credential_source with role_arn",
+		`profiles[1].role_arn: "not-an-arn" isn't an IAM role ARN`,
+		"profiles[1].set.sso_role_name: set it with the profile's own fields",
+	}
+	if !reflect.DeepEqual(validationErr.Problems, want) {
+		t.Errorf("problems =\n%s\nwant\n%s", strings.Join(validationErr.Problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(`[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+
+[profile Acme Production_Admin]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = Admin
+region = us-east-1
+wasp_account_name = Acme Production
+
+[profile prod-ro]
+sso_session = corp
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+region = eu-west-1
+output = json
+
+[profile ci]
+role_arn = arn:aws:iam::111111111111:role/CI
+credential_source = Ec2InstanceMetadata
+region = us-east-1
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := awsconfig.NewFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := &waspconfig.Config{
+		Version:  waspconfig.Version,
+		Naming:   waspconfig.Naming{Template: "{{.AccountName}}_{{.RoleName}}"},
+		Defaults: map[string]string{"region": "us-east-1"},
+		Aliases:  map[string]string{"prod": "Acme Production_Admin"},
+	}
+	m, err := Generate("platform", cf, wc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantSessions := []Session{{
+		Name:     "corp",
+		StartURL: "https://corp.awsapps.com/start",
+		Region:   "us-east-1",
+		Accounts: []Account{{ID: "111111111111", Name: "Acme Production", Roles: []string{"Admin"}}},
+	}}
+	if !reflect.DeepEqual(m.Sessions, wantSessions) {
+		t.Errorf("sessions = %+v, want %+v", m.Sessions, wantSessions)
+	}
+	// Only keys the defaults don't give are kept
+	wantProfiles := []Profile{
+		{Name: "prod-ro", Session: "corp", AccountID: "111111111111", Role: "ReadOnly", Set: map[string]string{"output": "json", "region": "eu-west-1"}},
+		{Name: "ci", RoleARN: "arn:aws:iam::111111111111:role/CI", CredentialSource: "Ec2InstanceMetadata"},
+	}
+	if !reflect.DeepEqual(m.Profiles, wantProfiles) {
+		t.Errorf("profiles = %+v, want %+v", m.Profiles, wantProfiles)
+	}
+
+	// What Generate writes, Load reads back the same
+	var b strings.Builder
+	if err := m.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.path = ""
+	if !reflect.DeepEqual(loaded, m) {
+		t.Errorf("round trip = %+v, want %+v", loaded, m)
+	}
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Format is a machine or human readable output format
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	CSV   Format = "csv"
+)
+
+// Formats lists every supported format
+var Formats = []Format{Table, JSON, YAML, CSV}
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// Data is something that can be written in any format. Headers and Rows
+// are used for table and CSV output; Records is marshalled as is for JSON
+// and YAML so structured fields like lists survive.
+type Data struct {
+	Headers []string
+	Rows    [][]string
+	Records any
+}
+
+// Write renders data to w in the given format
+func Write(w io.Writer, format Format, data Data) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data.Records)
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(data.Records); err != nil {
+			return err
+		}
+		return enc.Close()
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(data.Headers); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(data.Rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case Table, "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(data.Headers, "\t")))
+		for _, row := range data.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	type record struct {
+		Name  string   `json:"name" yaml:"name"`
+		Roles []string `json:"roles" yaml:"roles"`
+	}
+	data := Data{
+		Headers: []string{"Name", "Roles"},
+		Rows:    [][]string{{"prod", "Admin,ReadOnly"}},
+		Records: []record{{"prod", []string{"Admin", "ReadOnly"}}},
+	}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{Table, "NAME  ROLES\nprod  Admin,ReadOnly\n"},
+		{CSV, "Name,Roles\nprod,\"Admin,ReadOnly\"\n"},
+		{JSON, "[\n  {\n    \"name\": \"prod\",\n    \"roles\": [\n      \"Admin\",\n      \"ReadOnly\"\n    ]\n  }\n]\n"},
+		{YAML, "- name: prod\n  roles:\n    - Admin\n    - ReadOnly\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, test.format, data); err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected\n%q\ngot\n%q", test.format, test.expected, buf.String())
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSON"); err != nil || f != JSON {
+		t.Errorf("expected JSON, got %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
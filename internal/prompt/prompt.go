@@ -0,0 +1,293 @@
+package prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+)
+
+// DefaultFormat shows the profile (or its alias), its tags and how long
+// its SSO token has left, e.g. "prod [prod] 7h12m"
+const DefaultFormat = `{{.Name}}{{with .Tags}} [{{join . ","}}]{{end}}{{with .Remaining}} {{.}}{{end}}`
+
+// Index is what wasp prompt needs to know about every profile. It is
+// cached as JSON in ~/.wasp/cache/prompt.json so drawing a prompt doesn't
+// parse the AWS and wasp config files, and is built again once one of the
+// files it came from changes.
+type Index struct {
+	// Config is the wasp config file the index was built with
+	Config   string            `json:"config"`
+	Format   string            `json:"format,omitempty"`
+	Files    []Stamp           `json:"files"`
+	Profiles map[string]Entry  `json:"profiles"`
+	Aliases  map[string]string `json:"aliases,omitempty"`
+
+	path string
+}
+
+// Entry is a profile in the index. Token is the SSO token cache key of
+// the session the profile signs in with, if any.
+type Entry struct {
+	AccountID   string   `json:"account_id,omitempty"`
+	AccountName string   `json:"account_name,omitempty"`
+	Role        string   `json:"role,omitempty"`
+	Session     string   `json:"session,omitempty"`
+	Token       string   `json:"token,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Color       string   `json:"color,omitempty"`
+}
+
+// Stamp identifies the version of a file by its size and modification
+// time. Missing files have a zero stamp.
+type Stamp struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mod_time,omitzero"`
+	Size    int64     `json:"size,omitempty"`
+}
+
+// Stamps returns the current stamps of files
+func Stamps(paths ...string) []Stamp {
+	stamps := make([]Stamp, len(paths))
+	for i, path := range paths {
+		stamps[i].Path = path
+		if info, err := os.Stat(path); err == nil {
+			stamps[i].ModTime = info.ModTime()
+			stamps[i].Size = info.Size()
+		}
+	}
+	return stamps
+}
+
+// New returns an empty index to fill in and save
+func New() (*Index, error) {
+	path, err := waspdir.Path("cache", "prompt.json")
+	if err != nil {
+		return nil, err
+	}
+	return &Index{Profiles: make(map[string]Entry), path: path}, nil
+}
+
+// Load reads the cached index
+func Load() (*Index, error) {
+	idx, err := New()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Save writes the index atomically, so a prompt drawn in another shell
+// never sees half of it
+func (idx *Index) Save() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), ".prompt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), idx.path)
+}
+
+// Fresh reports whether the index was built with the wasp config file at
+// config and none of its files changed since
+func (idx *Index) Fresh(config string) bool {
+	if idx.Config != config || len(idx.Files) == 0 {
+		return false
+	}
+	for i, stamp := range Stamps(idx.paths()...) {
+		old := idx.Files[i]
+		if stamp.Size != old.Size || !stamp.ModTime.Equal(old.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+func (idx *Index) paths() []string {
+	paths := make([]string, len(idx.Files))
+	for i, stamp := range idx.Files {
+		paths[i] = stamp.Path
+	}
+	return paths
+}
+
+// Resolve returns the profile an alias stands for, or name itself
+func (idx *Index) Resolve(name string) string {
+	if profile, ok := idx.Aliases[name]; ok {
+		if _, ok := idx.Profiles[name]; !ok {
+			return profile
+		}
+	}
+	return name
+}
+
+// Data is what prompt formats can refer to. Name is the first alias of
+// the profile, or the profile's name. Remaining is how long the SSO token
+// has left, "expired" when there's no valid token and empty for profiles
+// that don't sign in with SSO.
+type Data struct {
+	Name        string
+	Profile     string
+	Alias       string
+	AccountID   string
+	AccountName string
+	Role        string
+	Session     string
+	Tags        []string
+	Color       string
+	ExpiresAt   time.Time
+	Remaining   string
+}
+
+// Data returns what a prompt shows for a profile
+func (idx *Index) Data(profile string) Data {
+	e := idx.Profiles[profile]
+	d := Data{
+		Name:        profile,
+		Profile:     profile,
+		AccountID:   e.AccountID,
+		AccountName: e.AccountName,
+		Role:        e.Role,
+		Session:     e.Session,
+		Tags:        e.Tags,
+		Color:       e.Color,
+	}
+	for alias, target := range idx.Aliases {
+		if target == profile && (d.Alias == "" || alias < d.Alias) {
+			d.Alias = alias
+		}
+	}
+	if d.Alias != "" {
+		d.Name = d.Alias
+	}
+	return d
+}
+
+// SetExpiry fills in when the profile's SSO token expires. A zero
+// expiresAt means there's no token.
+func (d *Data) SetExpiry(expiresAt, now time.Time) {
+	d.ExpiresAt = expiresAt
+	d.Remaining = Lifetime(expiresAt.Sub(now))
+}
+
+// Lifetime formats how long a token has left, e.g. "7h12m" or "45m"
+func Lifetime(left time.Duration) string {
+	if left <= 0 {
+		return "expired"
+	}
+	left = left.Truncate(time.Minute)
+	if left >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(left.Hours()), int(left.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(left.Minutes()))
+}
+
+// Parse parses a prompt format, DefaultFormat if it's empty. Formats are
+// Go templates executed with a Data; {{template "default" .}} is the
+// default segment.
+func Parse(format string) (*template.Template, error) {
+	tmpl, err := template.New("default").Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}).Parse(DefaultFormat)
+	if err != nil || format == "" {
+		return tmpl, err
+	}
+	return tmpl.New("format").Parse(format)
+}
+
+// Check reports a format that doesn't parse or refers to something a Data
+// doesn't have
+func Check(format string) error {
+	tmpl, err := Parse(format)
+	if err != nil {
+		return err
+	}
+	_, err = Render(tmpl, Data{Name: "prod", Profile: "Acme_ReadOnly", Tags: []string{"prod"}}, ShellNone)
+	return err
+}
+
+// Shell picks how colors are written: not at all, as plain ANSI escapes,
+// or marked as zero width for bash's or zsh's prompt
+type Shell string
+
+const (
+	ShellNone Shell = "none"
+	ShellANSI Shell = "ansi"
+	ShellBash Shell = "bash"
+	ShellZsh  Shell = "zsh"
+)
+
+// Shells lists the shells colors can be written for
+var Shells = []Shell{ShellNone, ShellANSI, ShellBash, ShellZsh}
+
+// Render executes a parsed format and colors the result for a shell
+func Render(tmpl *template.Template, d Data, shell Shell) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, d); err != nil {
+		return "", err
+	}
+	text := b.String()
+	if shell == ShellZsh {
+		text = strings.ReplaceAll(text, "%", "%%")
+	}
+	start := colorCode(d.Color)
+	if text == "" || start == "" || shell == ShellNone || shell == "" {
+		return text, nil
+	}
+	const reset = "\x1b[0m"
+	switch shell {
+	case ShellBash:
+		// \001 and \002 mark escapes to readline; \[ and \] aren't
+		// expanded in the output of command substitutions
+		return "\x01" + start + "\x02" + text + "\x01" + reset + "\x02", nil
+	case ShellZsh:
+		return "%{" + start + "%}" + text + "%{" + reset + "%}", nil
+	}
+	return start + text + reset, nil
+}
+
+// colorCode returns the escape that sets the foreground to an ANSI 256
+// color number or #rrggbb
+func colorCode(color string) string {
+	if hex, ok := strings.CutPrefix(color, "#"); ok && len(hex) == 6 {
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", rgb>>16, rgb>>8&0xff, rgb&0xff)
+	}
+	if n, err := strconv.Atoi(color); err == nil && n >= 0 && n < 256 {
+		return fmt.Sprintf("\x1b[38;5;%dm", n)
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLifetime(t *testing.T) {
+	tests := []struct {
+		left time.Duration
+		want string
+	}{
+		{-time.Minute, "expired"},
+		{0, "expired"},
+		{30 * time.Second, "0m"},
+		{45*time.Minute + 59*time.Second, "45m"},
+		{7*time.Hour + 5*time.Minute, "7h05m"},
+	}
+	for _, tt := range tests {
+		if got := Lifetime(tt.left); got != tt.want {
+			t.Errorf("Lifetime(%v) = %q, want %q", tt.left, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	idx := &Index{
+		Profiles: map[string]Entry{
+			"Acme Production_Admin": {AccountName: "Acme Production", Role: "Admin", Tags: []string{"prod"}, Color: "196"},
+			"Acme Dev_Admin":        {AccountName: "Acme Dev", Role: "Admin"},
+		},
+		Aliases: map[string]string{"prod": "Acme Production_Admin", "p": "Acme Production_Admin"},
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	prod := idx.Data(idx.Resolve("prod"))
+	prod.SetExpiry(now.Add(90*time.Minute), now)
+	dev := idx.Data("Acme Dev_Admin")
+
+	tests := []struct {
+		name   string
+		format string
+		data   Data
+		shell  Shell
+		want   string
+	}{
+		{"default", "", prod, ShellNone, "p [prod] 1h30m"},
+		{"no tags or token", "", dev, ShellANSI, "Acme Dev_Admin"},
+		{"custom", "{{.AccountName}}/{{.Role}} {{template \"default\" .}}", prod, ShellNone, "Acme Production/Admin p [prod] 1h30m"},
+		{"ansi", "{{.Profile}}", prod, ShellANSI, "\x1b[38;5;196mAcme Production_Admin\x1b[0m"},
+		{"bash", "{{.Alias}}", prod, ShellBash, "\x01\x1b[38;5;196m\x02p\x01\x1b[0m\x02"},
+		{"zsh", "{{.Alias}} 100%", prod, ShellZsh, "%{\x1b[38;5;196m%}p 100%%%{\x1b[0m%}"},
+		{"hex color", "{{.Alias}}", Data{Alias: "x", Color: "#ff8000"}, ShellANSI, "\x1b[38;2;255;128;0mx\x1b[0m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Render(tmpl, tt.data, tt.shell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, format := range []string{"", "{{.Alias}} {{template \"default\" .}}", "{{join .Tags \",\"}}"} {
+		if err := Check(format); err != nil {
+			t.Errorf("Check(%q) = %v", format, err)
+		}
+	}
+	for _, format := range []string{"{{.Nope}}", "{{.Name", "{{nope .Name}}"} {
+		if err := Check(format); err == nil {
+			t.Errorf("Check(%q) = nil, want an error", format)
+		}
+	}
+}
+
+func TestIndexFresh(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WASP_HOME", dir)
+	config := filepath.Join(dir, "config.yaml")
+	awsConfig := filepath.Join(dir, "aws-config")
+	if err := os.WriteFile(config, []byte("version: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Config = config
+	idx.Files = Stamps(config, awsConfig)
+	idx.Profiles["a"] = Entry{Session: "corp", Token: "corp"}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Fresh(config) {
+		t.Error("Fresh() = false for unchanged files")
+	}
+	if loaded.Fresh(filepath.Join(dir, "other.yaml")) {
+		t.Error("Fresh() = true for another wasp config file")
+	}
+	if loaded.Profiles["a"].Token != "corp" {
+		t.Errorf("Profiles[a] = %+v after loading", loaded.Profiles["a"])
+	}
+
+	// A file that didn't exist when the index was built
+	if err := os.WriteFile(awsConfig, []byte("[profile a]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Fresh(config) {
+		t.Error("Fresh() = true after a file was created")
+	}
+}
@@ -0,0 +1,64 @@
+package ssocache
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry is a cached token file found in the cache directory
+type Entry struct {
+	Path  string
+	Token *Token
+}
+
+// Dir returns the directory the AWS CLI and SDKs cache SSO tokens in
+func Dir() (string, error) {
+	path, err := Path("")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(path), nil
+}
+
+// List returns every access token cached in dir, sorted by path. Client
+// registrations and files that can't be parsed are skipped.
+func List(dir string) ([]Entry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		token, err := Load(path)
+		if err != nil || token.AccessToken == "" {
+			continue
+		}
+		entries = append(entries, Entry{Path: path, Token: token})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// Remove deletes a cached token file. It refuses to remove anything that
+// isn't a token file directly inside dir.
+func Remove(dir, path string) error {
+	if filepath.Dir(path) != filepath.Clean(dir) {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrPermission}
+	}
+	if _, err := Load(path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,170 @@
+package ssocache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+)
+
+// expiryWindow is how long before the real expiry a token is treated as
+// expired, so it isn't handed out moments before it stops working.
+const expiryWindow = 5 * time.Minute
+
+// ErrNoRefreshToken is returned by Refresh when the token can't be refreshed
+var ErrNoRefreshToken = errors.New("cached token has no usable refresh token")
+
+// Token is an SSO access token as cached by the AWS CLI and SDKs in
+// ~/.aws/sso/cache. Fields wasp doesn't know about are preserved when the
+// token is written back.
+type Token struct {
+	StartURL              string    `json:"startUrl,omitempty"`
+	Region                string    `json:"region,omitempty"`
+	AccessToken           string    `json:"accessToken,omitempty"`
+	ExpiresAt             time.Time `json:"expiresAt,omitzero"`
+	ClientID              string    `json:"clientId,omitempty"`
+	ClientSecret          string    `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt time.Time `json:"registrationExpiresAt,omitzero"`
+	RefreshToken          string    `json:"refreshToken,omitempty"`
+
+	unknown map[string]json.RawMessage
+}
+
+// TokenCreator is the subset of the SSO OIDC client used to refresh tokens
+type TokenCreator interface {
+	CreateToken(ctx context.Context, params *ssooidc.CreateTokenInput, optFns ...func(*ssooidc.Options)) (*ssooidc.CreateTokenOutput, error)
+}
+
+// Path returns the cache file for an SSO session (or legacy start URL)
+func Path(key string) (string, error) {
+	return ssocreds.StandardCachedTokenFilepath(key)
+}
+
+// Load reads a cached token from disk
+func Load(path string) (*Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Token
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parsing cached token %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Save writes the token to disk atomically so concurrent readers (including
+// the AWS CLI) never see a partially written file.
+func (t *Token) Save(path string) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wasp-token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Expired reports whether the access token is missing or about to expire
+func (t *Token) Expired(now time.Time) bool {
+	return t.AccessToken == "" || !now.Add(expiryWindow).Before(t.ExpiresAt)
+}
+
+// CanRefresh reports whether the token carries a refresh token and a client
+// registration that is still valid.
+func (t *Token) CanRefresh(now time.Time) bool {
+	if t.RefreshToken == "" || t.ClientID == "" || t.ClientSecret == "" {
+		return false
+	}
+	return t.RegistrationExpiresAt.IsZero() || now.Before(t.RegistrationExpiresAt)
+}
+
+// Refresh exchanges the refresh token for a new access token. The token is
+// updated in place; callers are responsible for saving it.
+func (t *Token) Refresh(ctx context.Context, client TokenCreator, now time.Time) error {
+	if !t.CanRefresh(now) {
+		return ErrNoRefreshToken
+	}
+	out, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(t.ClientID),
+		ClientSecret: aws.String(t.ClientSecret),
+		GrantType:    aws.String("refresh_token"),
+		RefreshToken: aws.String(t.RefreshToken),
+	})
+	if err != nil {
+		return fmt.Errorf("refreshing SSO token: %w", err)
+	}
+	t.AccessToken = aws.ToString(out.AccessToken)
+	t.ExpiresAt = now.Add(time.Duration(out.ExpiresIn) * time.Second).UTC().Truncate(time.Second)
+	if out.RefreshToken != nil {
+		t.RefreshToken = aws.ToString(out.RefreshToken)
+	}
+	return nil
+}
+
+func (t *Token) UnmarshalJSON(data []byte) error {
+	type known Token
+	if err := json.Unmarshal(data, (*known)(t)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &t.unknown); err != nil {
+		return err
+	}
+	for _, key := range knownKeys {
+		delete(t.unknown, key)
+	}
+	return nil
+}
+
+func (t Token) MarshalJSON() ([]byte, error) {
+	type known Token
+	data, err := json.Marshal(known(t))
+	if err != nil || len(t.unknown) == 0 {
+		return data, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range t.unknown {
+		if _, ok := fields[k]; !ok {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+var knownKeys = []string{
+	"startUrl",
+	"region",
+	"accessToken",
+	"expiresAt",
+	"clientId",
+	"clientSecret",
+	"registrationExpiresAt",
+	"refreshToken",
+}
@@ -0,0 +1,145 @@
+package ssocache
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/buzzsurfr/wasp/internal/ssoemulator"
+)
+
+func TestSavePreservesUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	raw := `{"startUrl":"https://example.awsapps.com/start","region":"us-east-1","accessToken":"abc",` +
+		`"expiresAt":"2024-05-01T12:00:00Z","refreshToken":"def","clientId":"id","clientSecret":"secret",` +
+		`"registrationExpiresAt":"2024-08-01T12:00:00Z","identityProvider":"custom"}`
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.RefreshToken != "def" || token.ClientSecret != "secret" || token.RegistrationExpiresAt.IsZero() {
+		t.Fatalf("refresh fields were not parsed: %+v", token)
+	}
+
+	token.AccessToken = "updated"
+	if err := token.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"accessToken":"updated"`, `"identityProvider":"custom"`, `"refreshToken":"def"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved token is missing %s: %s", want, data)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		token   Token
+		expired bool
+	}{
+		{Token{AccessToken: "a", ExpiresAt: now.Add(time.Hour)}, false},
+		{Token{AccessToken: "a", ExpiresAt: now.Add(time.Minute)}, true},
+		{Token{AccessToken: "a", ExpiresAt: now.Add(-time.Hour)}, true},
+		{Token{ExpiresAt: now.Add(time.Hour)}, true},
+	}
+	for _, test := range tests {
+		if got := test.token.Expired(now); got != test.expired {
+			t.Errorf("Expired(%v) = %v, expected %v", test.token.ExpiresAt, got, test.expired)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	fixture, err := ssoemulator.ParseFixture([]byte("accounts: []\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ssoemulator.New(fixture)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := ssooidc.NewFromConfig(aws.Config{Region: "us-east-1"}, func(o *ssooidc.Options) {
+		o.BaseEndpoint = aws.String(ts.URL)
+	})
+
+	accessToken, refreshToken, clientID, clientSecret := server.IssueToken()
+	now := time.Now()
+	token := &Token{
+		AccessToken:  accessToken,
+		ExpiresAt:    now.Add(-time.Minute),
+		RefreshToken: refreshToken,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+	if err := token.Refresh(context.Background(), client, now); err != nil {
+		t.Fatal(err)
+	}
+	if token.Expired(now) {
+		t.Error("refreshed token should not be expired")
+	}
+	if token.AccessToken == accessToken || token.RefreshToken == refreshToken {
+		t.Error("expected access and refresh tokens to be rotated")
+	}
+
+	token.RegistrationExpiresAt = now.Add(-time.Hour)
+	if err := token.Refresh(context.Background(), client, now); err != ErrNoRefreshToken {
+		t.Errorf("expected ErrNoRefreshToken for expired registration, got %v", err)
+	}
+}
+
+func TestListAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"token.json":        `{"startUrl":"https://example.awsapps.com/start","accessToken":"abc","expiresAt":"2024-05-01T12:00:00Z"}`,
+		"registration.json": `{"clientId":"id","clientSecret":"secret","expiresAt":"2024-08-01T12:00:00Z"}`,
+		"garbage.json":      `not json`,
+		"notes.txt":         `{"accessToken":"abc"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || filepath.Base(entries[0].Path) != "token.json" {
+		t.Fatalf("expected only token.json to be listed, got %v", entries)
+	}
+
+	if err := Remove(dir, filepath.Join(dir, "garbage.json")); err == nil {
+		t.Error("expected removing an unparsable file to fail")
+	}
+	if err := Remove(t.TempDir(), entries[0].Path); err == nil {
+		t.Error("expected removing a file outside the cache directory to fail")
+	}
+	if err := Remove(dir, entries[0].Path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(entries[0].Path); !os.IsNotExist(err) {
+		t.Error("expected token file to be removed")
+	}
+}
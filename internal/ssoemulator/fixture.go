@@ -0,0 +1,71 @@
+package ssoemulator
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Fixture describes the accounts and roles served by the emulator
+type Fixture struct {
+	StartURL string        `yaml:"start_url"`
+	Region   string        `yaml:"region"`
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	Accounts []Account     `yaml:"accounts"`
+}
+
+// Account is a single AWS account assignment in the fixture
+type Account struct {
+	ID    string   `yaml:"id"`
+	Name  string   `yaml:"name"`
+	Email string   `yaml:"email"`
+	Roles []string `yaml:"roles"`
+}
+
+// LoadFixture reads a YAML fixture file from disk
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixture(data)
+}
+
+// ParseFixture parses a YAML fixture and fills in defaults
+func ParseFixture(data []byte) (*Fixture, error) {
+	var f Fixture
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing fixture: %w", err)
+	}
+	if f.Region == "" {
+		f.Region = "us-east-1"
+	}
+	if f.StartURL == "" {
+		f.StartURL = "https://emulator.awsapps.com/start"
+	}
+	if f.TokenTTL == 0 {
+		f.TokenTTL = 8 * time.Hour
+	}
+	seen := make(map[string]bool)
+	for _, account := range f.Accounts {
+		if account.ID == "" {
+			return nil, fmt.Errorf("account %q has no id", account.Name)
+		}
+		if seen[account.ID] {
+			return nil, fmt.Errorf("account %s is defined more than once", account.ID)
+		}
+		seen[account.ID] = true
+	}
+	return &f, nil
+}
+
+func (f *Fixture) account(id string) *Account {
+	for i := range f.Accounts {
+		if f.Accounts[i].ID == id {
+			return &f.Accounts[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package ssoemulator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	grantTypeDeviceCode   = "urn:ietf:params:oauth:grant-type:device_code"
+	grantTypeRefreshToken = "refresh_token"
+	bearerTokenHeader     = "X-Amz-Sso_bearer_token"
+	defaultPageSize       = 100
+)
+
+var errInvalidPagination = errors.New("invalid pagination parameters")
+
+// Server speaks enough of the AWS SSO portal and SSO OIDC wire protocols
+// for the SDK clients to be pointed at it with an endpoint override.
+// Device authorizations are approved immediately so logins never block.
+type Server struct {
+	fixture *Fixture
+	now     func() time.Time
+	mux     *http.ServeMux
+
+	mu            sync.Mutex
+	clients       map[string]string // clientId -> clientSecret
+	devices       map[string]string // deviceCode -> clientId
+	accessTokens  map[string]time.Time
+	refreshTokens map[string]string // refreshToken -> clientId
+}
+
+// New creates an emulator serving the given fixture
+func New(fixture *Fixture) *Server {
+	s := &Server{
+		fixture:       fixture,
+		now:           time.Now,
+		mux:           http.NewServeMux(),
+		clients:       make(map[string]string),
+		devices:       make(map[string]string),
+		accessTokens:  make(map[string]time.Time),
+		refreshTokens: make(map[string]string),
+	}
+
+	// SSO portal
+	s.mux.HandleFunc("GET /assignment/accounts", s.authorized(s.listAccounts))
+	s.mux.HandleFunc("GET /assignment/roles", s.authorized(s.listAccountRoles))
+	s.mux.HandleFunc("GET /federation/credentials", s.authorized(s.getRoleCredentials))
+	s.mux.HandleFunc("POST /logout", s.authorized(s.logout))
+
+	// SSO OIDC
+	s.mux.HandleFunc("POST /client/register", s.registerClient)
+	s.mux.HandleFunc("POST /device_authorization", s.startDeviceAuthorization)
+	s.mux.HandleFunc("POST /token", s.createToken)
+	s.mux.HandleFunc("GET /device", s.verify)
+
+	// Console federation
+	s.mux.HandleFunc("GET /federation", s.federation)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// IssueToken creates a valid access and refresh token pair without going
+// through device authorization. The returned client credentials can be used
+// to refresh the token.
+func (s *Server) IssueToken() (accessToken, refreshToken, clientID, clientSecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clientID, clientSecret = randomID("client"), randomID("secret")
+	s.clients[clientID] = clientSecret
+	accessToken, refreshToken = s.issueLocked(clientID)
+	return accessToken, refreshToken, clientID, clientSecret
+}
+
+// ExpireTokens marks every issued access token as expired. Refresh tokens
+// remain valid.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token := range s.accessTokens {
+		s.accessTokens[token] = time.Time{}
+	}
+}
+
+func (s *Server) issueLocked(clientID string) (accessToken, refreshToken string) {
+	accessToken, refreshToken = randomID("access"), randomID("refresh")
+	s.accessTokens[accessToken] = s.now().Add(s.fixture.TokenTTL)
+	s.refreshTokens[refreshToken] = clientID
+	return accessToken, refreshToken
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(bearerTokenHeader)
+		s.mu.Lock()
+		expiry, ok := s.accessTokens[token]
+		s.mu.Unlock()
+		if !ok || !s.now().Before(expiry) {
+			writeError(w, http.StatusUnauthorized, "UnauthorizedException", "Session token not found or invalid")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) listAccounts(w http.ResponseWriter, r *http.Request) {
+	type accountInfo struct {
+		AccountID    string `json:"accountId"`
+		AccountName  string `json:"accountName"`
+		EmailAddress string `json:"emailAddress"`
+	}
+	var list []accountInfo
+	for _, account := range s.fixture.Accounts {
+		list = append(list, accountInfo{account.ID, account.Name, account.Email})
+	}
+	page, next, err := paginate(list, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequestException", err.Error())
+		return
+	}
+	writeJSON(w, map[string]any{"accountList": page, "nextToken": next})
+}
+
+func (s *Server) listAccountRoles(w http.ResponseWriter, r *http.Request) {
+	type roleInfo struct {
+		AccountID string `json:"accountId"`
+		RoleName  string `json:"roleName"`
+	}
+	account := s.fixture.account(r.URL.Query().Get("account_id"))
+	if account == nil {
+		writeError(w, http.StatusNotFound, "ResourceNotFoundException", "Account not found")
+		return
+	}
+	var list []roleInfo
+	for _, role := range account.Roles {
+		list = append(list, roleInfo{account.ID, role})
+	}
+	page, next, err := paginate(list, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequestException", err.Error())
+		return
+	}
+	writeJSON(w, map[string]any{"roleList": page, "nextToken": next})
+}
+
+func (s *Server) getRoleCredentials(w http.ResponseWriter, r *http.Request) {
+	account := s.fixture.account(r.URL.Query().Get("account_id"))
+	role := r.URL.Query().Get("role_name")
+	if account == nil || !slices.Contains(account.Roles, role) {
+		writeError(w, http.StatusForbidden, "ForbiddenException", "No access")
+		return
+	}
+	writeJSON(w, map[string]any{
+		"roleCredentials": map[string]any{
+			"accessKeyId":     "ASIA" + account.ID,
+			"secretAccessKey": randomID("secret"),
+			"sessionToken":    randomID("session"),
+			"expiration":      s.now().Add(time.Hour).UnixMilli(),
+		},
+	})
+}
+
+// federation issues sign-in tokens for the role credentials it handed out.
+// The login action is left to the browser, so it isn't served.
+func (s *Server) federation(w http.ResponseWriter, r *http.Request) {
+	if action := r.URL.Query().Get("Action"); action != "getSigninToken" {
+		http.Error(w, "unsupported Action "+action, http.StatusBadRequest)
+		return
+	}
+	var session struct {
+		SessionID    string `json:"sessionId"`
+		SessionKey   string `json:"sessionKey"`
+		SessionToken string `json:"sessionToken"`
+	}
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("Session")), &session); err != nil || session.SessionKey == "" || session.SessionToken == "" {
+		http.Error(w, "invalid Session", http.StatusBadRequest)
+		return
+	}
+	id, ok := strings.CutPrefix(session.SessionID, "ASIA")
+	if !ok || s.fixture.account(id) == nil {
+		http.Error(w, "invalid Session", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]any{"SigninToken": randomID("signin")})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delete(s.accessTokens, r.Header.Get(bearerTokenHeader))
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) registerClient(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		ClientName string `json:"clientName"`
+		ClientType string `json:"clientType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.ClientName == "" {
+		writeOIDCError(w, http.StatusBadRequest, "InvalidRequestException", "invalid_request")
+		return
+	}
+	now := s.now()
+	clientID, clientSecret := randomID("client"), randomID("secret")
+	s.mu.Lock()
+	s.clients[clientID] = clientSecret
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{
+		"clientId":              clientID,
+		"clientSecret":          clientSecret,
+		"clientIdIssuedAt":      now.Unix(),
+		"clientSecretExpiresAt": now.Add(90 * 24 * time.Hour).Unix(),
+	})
+}
+
+func (s *Server) startDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		ClientID     string `json:"clientId"`
+		ClientSecret string `json:"clientSecret"`
+		StartURL     string `json:"startUrl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeOIDCError(w, http.StatusBadRequest, "InvalidRequestException", "invalid_request")
+		return
+	}
+	if !s.validClient(in.ClientID, in.ClientSecret) {
+		writeOIDCError(w, http.StatusUnauthorized, "InvalidClientException", "invalid_client")
+		return
+	}
+	deviceCode, userCode := randomID("device"), randomID("")[:8]
+	s.mu.Lock()
+	s.devices[deviceCode] = in.ClientID
+	s.mu.Unlock()
+	verificationURI := "http://" + r.Host + "/device"
+	writeJSON(w, map[string]any{
+		"deviceCode":              deviceCode,
+		"userCode":                userCode,
+		"verificationUri":         verificationURI,
+		"verificationUriComplete": verificationURI + "?user_code=" + userCode,
+		"expiresIn":               600,
+		"interval":                1,
+	})
+}
+
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		ClientID     string `json:"clientId"`
+		ClientSecret string `json:"clientSecret"`
+		GrantType    string `json:"grantType"`
+		DeviceCode   string `json:"deviceCode"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeOIDCError(w, http.StatusBadRequest, "InvalidRequestException", "invalid_request")
+		return
+	}
+	if !s.validClient(in.ClientID, in.ClientSecret) {
+		writeOIDCError(w, http.StatusUnauthorized, "InvalidClientException", "invalid_client")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch in.GrantType {
+	case grantTypeDeviceCode:
+		if s.devices[in.DeviceCode] != in.ClientID {
+			writeOIDCError(w, http.StatusBadRequest, "InvalidGrantException", "invalid_grant")
+			return
+		}
+		delete(s.devices, in.DeviceCode)
+	case grantTypeRefreshToken:
+		if s.refreshTokens[in.RefreshToken] != in.ClientID {
+			writeOIDCError(w, http.StatusBadRequest, "InvalidGrantException", "invalid_grant")
+			return
+		}
+		delete(s.refreshTokens, in.RefreshToken)
+	default:
+		writeOIDCError(w, http.StatusBadRequest, "UnsupportedGrantTypeException", "unsupported_grant_type")
+		return
+	}
+	accessToken, refreshToken := s.issueLocked(in.ClientID)
+	writeJSON(w, map[string]any{
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+		"tokenType":    "Bearer",
+		"expiresIn":    int(s.fixture.TokenTTL.Seconds()),
+	})
+}
+
+// verify is the page a user would visit to approve a device. Devices are
+// already approved, so it only confirms that.
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("wasp SSO emulator: device approved\n"))
+}
+
+func (s *Server) validClient(id, secret string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.clients[id]
+	return ok && stored == secret
+}
+
+func paginate[T any](list []T, r *http.Request) ([]T, *string, error) {
+	start, size := 0, defaultPageSize
+	var err error
+	if v := r.URL.Query().Get("next_token"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil || start < 0 || start > len(list) {
+			return nil, nil, errInvalidPagination
+		}
+	}
+	if v := r.URL.Query().Get("max_result"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil || size < 1 {
+			return nil, nil, errInvalidPagination
+		}
+	}
+	end := min(start+size, len(list))
+	page := list[start:end]
+	if page == nil {
+		page = []T{}
+	}
+	if end < len(list) {
+		next := strconv.Itoa(end)
+		return page, &next, nil
+	}
+	return page, nil, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func writeOIDCError(w http.ResponseWriter, status int, code, oauthError string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": oauthError})
+}
+
+func randomID(prefix string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	if prefix == "" {
+		return hex.EncodeToString(b)
+	}
+	return prefix + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,195 @@
+package ssoemulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+)
+
+const testFixture = `
+start_url: https://example.awsapps.com/start
+region: us-east-1
+accounts:
+  - id: "111111111111"
+    name: Acme Production
+    email: prod@example.com
+    roles: [AdministratorAccess, ReadOnlyAccess]
+  - id: "222222222222"
+    name: Acme Development
+    email: dev@example.com
+    roles: [AdministratorAccess]
+`
+
+func newTestClients(t *testing.T) (*Server, *sso.Client, *ssooidc.Client) {
+	t.Helper()
+	fixture, err := ParseFixture([]byte(testFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := New(fixture)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	cfg := aws.Config{Region: fixture.Region}
+	ssoClient := sso.NewFromConfig(cfg, func(o *sso.Options) {
+		o.BaseEndpoint = aws.String(ts.URL)
+	})
+	oidcClient := ssooidc.NewFromConfig(cfg, func(o *ssooidc.Options) {
+		o.BaseEndpoint = aws.String(ts.URL)
+	})
+	return server, ssoClient, oidcClient
+}
+
+func TestDeviceLoginAndDiscovery(t *testing.T) {
+	ctx := context.Background()
+	_, ssoClient, oidcClient := newTestClients(t)
+
+	client, err := oidcClient.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String("wasp-test"),
+		ClientType: aws.String("public"),
+	})
+	if err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+	device, err := oidcClient.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     client.ClientId,
+		ClientSecret: client.ClientSecret,
+		StartUrl:     aws.String("https://example.awsapps.com/start"),
+	})
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization: %v", err)
+	}
+	token, err := oidcClient.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     client.ClientId,
+		ClientSecret: client.ClientSecret,
+		GrantType:    aws.String(grantTypeDeviceCode),
+		DeviceCode:   device.DeviceCode,
+	})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	accounts, err := ssoClient.ListAccounts(ctx, &sso.ListAccountsInput{
+		AccessToken: token.AccessToken,
+		MaxResults:  aws.Int32(1),
+	})
+	if err != nil {
+		t.Fatalf("ListAccounts: %v", err)
+	}
+	if len(accounts.AccountList) != 1 || accounts.NextToken == nil {
+		t.Fatalf("expected a single page with a next token, got %d accounts", len(accounts.AccountList))
+	}
+
+	roles, err := ssoClient.ListAccountRoles(ctx, &sso.ListAccountRolesInput{
+		AccessToken: token.AccessToken,
+		AccountId:   aws.String("111111111111"),
+	})
+	if err != nil {
+		t.Fatalf("ListAccountRoles: %v", err)
+	}
+	if len(roles.RoleList) != 2 {
+		t.Errorf("expected 2 roles, got %d", len(roles.RoleList))
+	}
+
+	creds, err := ssoClient.GetRoleCredentials(ctx, &sso.GetRoleCredentialsInput{
+		AccessToken: token.AccessToken,
+		AccountId:   aws.String("111111111111"),
+		RoleName:    aws.String("ReadOnlyAccess"),
+	})
+	if err != nil {
+		t.Fatalf("GetRoleCredentials: %v", err)
+	}
+	if aws.ToString(creds.RoleCredentials.AccessKeyId) == "" {
+		t.Error("expected an access key id")
+	}
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	ctx := context.Background()
+	server, ssoClient, oidcClient := newTestClients(t)
+
+	accessToken, refreshToken, clientID, clientSecret := server.IssueToken()
+	server.ExpireTokens()
+
+	var unauthorized *types.UnauthorizedException
+	_, err := ssoClient.ListAccounts(ctx, &sso.ListAccountsInput{AccessToken: aws.String(accessToken)})
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedException for expired token, got %v", err)
+	}
+
+	token, err := oidcClient.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(clientID),
+		ClientSecret: aws.String(clientSecret),
+		GrantType:    aws.String(grantTypeRefreshToken),
+		RefreshToken: aws.String(refreshToken),
+	})
+	if err != nil {
+		t.Fatalf("CreateToken with refresh token: %v", err)
+	}
+	if _, err := ssoClient.ListAccounts(ctx, &sso.ListAccountsInput{AccessToken: token.AccessToken}); err != nil {
+		t.Fatalf("ListAccounts after refresh: %v", err)
+	}
+
+	// Refresh tokens are single use
+	_, err = oidcClient.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(clientID),
+		ClientSecret: aws.String(clientSecret),
+		GrantType:    aws.String(grantTypeRefreshToken),
+		RefreshToken: aws.String(refreshToken),
+	})
+	if err == nil {
+		t.Error("expected reused refresh token to be rejected")
+	}
+
+	_, err = ssoClient.Logout(ctx, &sso.LogoutInput{AccessToken: aws.String("unknown")})
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedException for logout with an unknown token, got %v", err)
+	}
+	if _, err := ssoClient.Logout(ctx, &sso.LogoutInput{AccessToken: token.AccessToken}); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	_, err = ssoClient.ListAccounts(ctx, &sso.ListAccountsInput{AccessToken: token.AccessToken})
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedException after logout, got %v", err)
+	}
+	_, err = ssoClient.Logout(ctx, &sso.LogoutInput{AccessToken: token.AccessToken})
+	if !errors.As(err, &unauthorized) {
+		t.Fatalf("expected UnauthorizedException for a second logout, got %v", err)
+	}
+}
+
+func TestFederation(t *testing.T) {
+	fixture, err := ParseFixture([]byte(testFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(New(fixture))
+	defer ts.Close()
+
+	for session, want := range map[string]int{
+		`{"sessionId":"ASIA111111111111","sessionKey":"secret","sessionToken":"token"}`: http.StatusOK,
+		`{"sessionId":"ASIA999999999999","sessionKey":"secret","sessionToken":"token"}`: http.StatusBadRequest,
+		`{"sessionId":"ASIA111111111111","sessionKey":"secret"}`:                        http.StatusBadRequest,
+	} {
+		query := url.Values{"Action": {"getSigninToken"}, "Session": {session}}
+		resp, err := http.Get(ts.URL + "/federation?" + query.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct{ SigninToken string }
+		json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if resp.StatusCode != want || want == http.StatusOK && out.SigninToken == "" {
+			t.Errorf("getSigninToken(%s) = %d %q, want %d", session, resp.StatusCode, out.SigninToken, want)
+		}
+	}
+}
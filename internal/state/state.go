@@ -0,0 +1,127 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+)
+
+// maxHistory caps how many profile switches are remembered
+const maxHistory = 200
+
+// State is what wasp remembers between runs about how profiles are used.
+// It is stored as JSON in ~/.wasp/state.
+type State struct {
+	Favorites []string `json:"favorites,omitempty"`
+	History   []Entry  `json:"history,omitempty"`
+
+	path string
+}
+
+// Entry is a single profile switch. History is kept newest first.
+type Entry struct {
+	Profile string    `json:"profile"`
+	Time    time.Time `json:"time"`
+}
+
+// Load reads the state file. A missing file is an empty state.
+func Load() (*State, error) {
+	path, err := waspdir.Path("state")
+	if err != nil {
+		return nil, err
+	}
+	s := &State{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the state file
+func (s *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+// Record adds a profile switch to the history
+func (s *State) Record(profile string, now time.Time) {
+	s.History = slices.Insert(s.History, 0, Entry{Profile: profile, Time: now})
+	if len(s.History) > maxHistory {
+		s.History = s.History[:maxHistory]
+	}
+}
+
+// ClearHistory forgets every profile switch. Favorites are kept.
+func (s *State) ClearHistory() {
+	s.History = nil
+}
+
+// LastUsed returns when a profile was last switched to, or the zero time
+func (s *State) LastUsed(profile string) time.Time {
+	for _, e := range s.History {
+		if e.Profile == profile {
+			return e.Time
+		}
+	}
+	return time.Time{}
+}
+
+// IsFavorite reports whether a profile is starred
+func (s *State) IsFavorite(profile string) bool {
+	return slices.Contains(s.Favorites, profile)
+}
+
+// ToggleFavorite stars or unstars a profile and reports whether it is now
+// a favorite.
+func (s *State) ToggleFavorite(profile string) bool {
+	if i := slices.Index(s.Favorites, profile); i >= 0 {
+		s.Favorites = slices.Delete(s.Favorites, i, i+1)
+		return false
+	}
+	s.Favorites = append(s.Favorites, profile)
+	return true
+}
+
+// Sort orders profile names favorites first, then most recently used,
+// then by name.
+func (s *State) Sort(profiles []string) {
+	lastUsed := make(map[string]time.Time)
+	for i := len(s.History) - 1; i >= 0; i-- {
+		lastUsed[s.History[i].Profile] = s.History[i].Time
+	}
+	slices.SortStableFunc(profiles, func(a, b string) int {
+		if fa, fb := s.IsFavorite(a), s.IsFavorite(b); fa != fb {
+			if fa {
+				return -1
+			}
+			return 1
+		}
+		if c := lastUsed[b].Compare(lastUsed[a]); c != 0 {
+			return c
+		}
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSort(t *testing.T) {
+	now := time.Now()
+	s := &State{}
+	s.Record("dev", now.Add(-2*time.Hour))
+	s.Record("staging", now.Add(-time.Hour))
+	s.Record("dev", now)
+	s.ToggleFavorite("prod")
+
+	profiles := []string{"zeta", "staging", "prod", "alpha", "dev"}
+	s.Sort(profiles)
+	expected := []string{"prod", "dev", "staging", "alpha", "zeta"}
+	if !slices.Equal(profiles, expected) {
+		t.Errorf("Expected %v, but got %v", expected, profiles)
+	}
+
+	if s.ToggleFavorite("prod") {
+		t.Error("Expected toggling a favorite to unstar it")
+	}
+	if s.IsFavorite("prod") {
+		t.Error("Expected prod to no longer be a favorite")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("WASP_HOME", t.TempDir())
+
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	s.Record("dev", now)
+	s.ToggleFavorite("prod")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsFavorite("prod") || !loaded.LastUsed("dev").Equal(now) {
+		t.Errorf("Expected state to round trip, but got %+v", loaded)
+	}
+
+	loaded.ClearHistory()
+	if !loaded.LastUsed("dev").IsZero() || !loaded.IsFavorite("prod") {
+		t.Error("Expected clearing history to keep favorites")
+	}
+}
@@ -0,0 +1,161 @@
+package waspconfig
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	awsconfig "github.com/buzzsurfr/wasp/internal/awsconfig"
+)
+
+// DefaultChainTemplate names chained profiles after the SSO profile they
+// start from
+const DefaultChainTemplate = "{{.Profile}}_{{.AssumeRole}}"
+
+// Chain generates a profile that assumes a role from each SSO profile it
+// matches, e.g. OrganizationAccountAccessRole from every AdministratorAccess
+// profile. Session is a pattern like the Match fields.
+type Chain struct {
+	Session string `yaml:"session,omitempty"`
+	Match   `yaml:",inline"`
+
+	// Assume is a role name in the matched account, in the partition of
+	// the SSO session's region, or a template for the ARN of a role
+	// anywhere
+	Assume string `yaml:"assume"`
+
+	// Name is a template for the chained profile's name, executed with a
+	// ChainData
+	Name string `yaml:"name,omitempty"`
+
+	// Set adds keys to the chained profile, overriding defaults and rules
+	Set map[string]string `yaml:"set,omitempty"`
+}
+
+// Matches reports whether the chain starts from an account role's profile
+func (ch Chain) Matches(data NameData) bool {
+	return Rule{Session: ch.Session, Match: ch.Match}.Matches(data)
+}
+
+// ChainData is what chain name and ARN templates can refer to: the SSO
+// profile's account role, the SSO profile's name and the assumed role's
+// name
+type ChainData struct {
+	NameData
+	Profile    string
+	AssumeRole string
+}
+
+// ChainedProfile is a profile to generate for a chain
+type ChainedProfile struct {
+	Name          string
+	RoleARN       string
+	SourceProfile string
+	// Data is the account role of the SSO profile the chain starts from
+	Data     NameData
+	Settings []Setting
+	// Source is the chain that generated the profile, e.g. "chains[0]"
+	Source string
+}
+
+// Chained returns the chained profiles to generate from the planned SSO
+// profiles, sorted by name
+func (c *Config) Chained(planned []Planned) ([]ChainedProfile, error) {
+	ssoNames := make(map[string]bool)
+	for _, p := range planned {
+		ssoNames[p.Name] = true
+	}
+	byName := make(map[string]ChainedProfile)
+	var chained []ChainedProfile
+	for i, ch := range c.Chains {
+		at := fmt.Sprintf("chains[%d]", i)
+		for _, p := range planned {
+			if !ch.Matches(p.Data) {
+				continue
+			}
+			profile, err := ch.profile(at, p)
+			if err != nil {
+				return nil, err
+			}
+			if ssoNames[profile.Name] {
+				return nil, fmt.Errorf("%s: %q is already the name of an SSO profile; change the chain's name template", at, profile.Name)
+			}
+			if other, ok := byName[profile.Name]; ok {
+				if other.RoleARN == profile.RoleARN {
+					// Another SSO profile already leads to the same role
+					continue
+				}
+				return nil, fmt.Errorf("%s: %q would assume both %s and %s; put {{.Profile}} in the chain's name template", at, profile.Name, other.RoleARN, profile.RoleARN)
+			}
+			profile.Settings = c.Settings(p.Data)
+			for key, value := range ch.Set {
+				profile.Settings = setSetting(profile.Settings, Setting{key, value, at})
+			}
+			byName[profile.Name] = profile
+			chained = append(chained, profile)
+		}
+	}
+	sort.Slice(chained, func(i, j int) bool {
+		return chained[i].Name < chained[j].Name
+	})
+	return chained, nil
+}
+
+// profile builds the chained profile starting from an SSO profile
+func (ch Chain) profile(at string, p Planned) (ChainedProfile, error) {
+	data := ChainData{NameData: p.Data, Profile: p.Name, AssumeRole: ch.Assume}
+	arn := awsconfig.RoleARN(p.Data.SSORegion, p.Data.AccountID, ch.Assume)
+	if strings.HasPrefix(ch.Assume, "arn:") {
+		tmpl, err := newTemplate(at+".assume", ch.Assume)
+		if err != nil {
+			return ChainedProfile{}, err
+		}
+		if arn, err = execute(tmpl, data); err != nil {
+			return ChainedProfile{}, err
+		}
+	}
+	_, role, ok := awsconfig.ParseRoleARN(arn)
+	if !ok {
+		return ChainedProfile{}, fmt.Errorf("%s.assume: %q isn't an IAM role ARN", at, arn)
+	}
+	data.AssumeRole = role
+
+	text := ch.Name
+	if text == "" {
+		text = DefaultChainTemplate
+	}
+	tmpl, err := newTemplate(at+".name", text)
+	if err != nil {
+		return ChainedProfile{}, err
+	}
+	name, err := execute(tmpl, data)
+	if err != nil {
+		return ChainedProfile{}, err
+	}
+	return ChainedProfile{Name: name, RoleARN: arn, SourceProfile: p.Name, Data: p.Data, Source: at}, nil
+}
+
+func execute(tmpl *template.Template, data any) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// setSetting replaces or adds a setting, keeping the list sorted by key
+func setSetting(settings []Setting, s Setting) []Setting {
+	for i := range settings {
+		if settings[i].Key == s.Key {
+			settings[i] = s
+			return settings
+		}
+	}
+	settings = append(settings, s)
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Key < settings[j].Key
+	})
+	return settings
+}
@@ -0,0 +1,72 @@
+package waspconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChained(t *testing.T) {
+	c := loadString(t, `defaults:
+  region: us-east-1
+chains:
+  - role: Admin
+    assume: OrganizationAccountAccessRole
+    set:
+      duration_seconds: "3600"
+  - account: Acme Dev
+    role: Admin
+    assume: "arn:aws:iam::999999999999:role/deploy/{{lower .AccountName | replace \" \" \"-\"}}"
+    name: "{{.AccountName}}_deploy"
+`)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	dev := NameData{Session: "corp", AccountID: "222222222222", AccountName: "Acme Dev", RoleName: "Admin"}
+	prod := NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme Production", RoleName: "ReadOnly"}
+	planned := []Planned{{"Acme Dev_Admin", dev}, {"Acme Production_ReadOnly", prod}}
+
+	got, err := c.Chained(planned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChainedProfile{
+		{
+			Name:          "Acme Dev_Admin_OrganizationAccountAccessRole",
+			RoleARN:       "arn:aws:iam::222222222222:role/OrganizationAccountAccessRole",
+			SourceProfile: "Acme Dev_Admin",
+			Data:          dev,
+			Settings:      []Setting{{"duration_seconds", "3600", "chains[0]"}, {"region", "us-east-1", "defaults"}},
+			Source:        "chains[0]",
+		},
+		{
+			Name:          "Acme Dev_deploy",
+			RoleARN:       "arn:aws:iam::999999999999:role/deploy/acme-dev",
+			SourceProfile: "Acme Dev_Admin",
+			Data:          dev,
+			Settings:      []Setting{{"region", "us-east-1", "defaults"}},
+			Source:        "chains[1]",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chained() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// Roles in the matched account are in the SSO session's partition
+	gov := NameData{Session: "gov", SSORegion: "us-gov-west-1", AccountID: "333333333333", RoleName: "Admin"}
+	got, err = c.Chained([]Planned{{"Gov_Admin", gov}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].RoleARN != "arn:aws-us-gov:iam::333333333333:role/OrganizationAccountAccessRole" {
+		t.Errorf("Chained() = %+v, want an aws-us-gov role ARN", got)
+	}
+
+	// Two SSO profiles leading to different roles under one name
+	c.Chains[1].Name = "{{.AssumeRole}}"
+	c.Chains[1].Account = ""
+	c.Chains[1].Assume = "Deploy"
+	if _, err := c.Chained([]Planned{{"Acme Dev_Admin", dev}, {"Other_Admin", NameData{Session: "corp", AccountID: "333333333333", RoleName: "Admin"}}}); err == nil || !strings.Contains(err.Error(), "{{.Profile}}") {
+		t.Errorf("Chained() error = %v, want a name collision", err)
+	}
+}
@@ -0,0 +1,186 @@
+package waspconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Collision strategies for naming.collisions
+const (
+	// SuffixAccountID appends the account ID to colliding names
+	SuffixAccountID = "suffix-account-id"
+	// PrefixSession puts the SSO session in front of colliding names
+	PrefixSession = "prefix-session"
+	// FailOnCollision stops sync so the naming template can be fixed
+	FailOnCollision = "fail"
+)
+
+var collisionStrategies = []string{SuffixAccountID, PrefixSession, FailOnCollision}
+
+// Target is the role an SSO profile signs in to
+type Target struct {
+	Session   string
+	AccountID string
+	RoleName  string
+}
+
+func (d NameData) target() Target {
+	return Target{d.Session, d.AccountID, d.RoleName}
+}
+
+// Planned is the profile name chosen for an account role
+type Planned struct {
+	Name string
+	Data NameData
+}
+
+// Collision is a profile name wanted by more than one account role, or by
+// an account role and a profile that signs in somewhere else
+type Collision struct {
+	Name string
+	// Roles are the account roles that wanted the name
+	Roles []NameData
+	// Existing is set when a profile with the name signs in elsewhere
+	Existing bool
+	// Renamed maps account roles that lost the name to their new names
+	Renamed map[Target]string
+}
+
+func (c Collision) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q is wanted by", c.Name)
+	var parts []string
+	if c.Existing {
+		parts = append(parts, "an existing profile")
+	}
+	for _, d := range c.Roles {
+		part := fmt.Sprintf("%s in %s (%s) via %s", d.RoleName, d.AccountName, d.AccountID, d.Session)
+		if name, ok := c.Renamed[d.target()]; ok {
+			part += fmt.Sprintf(", renamed to %q", name)
+		}
+		parts = append(parts, part)
+	}
+	b.WriteString(" " + strings.Join(parts, "; "))
+	return b.String()
+}
+
+// CollisionError reports collisions when the strategy is to fail
+type CollisionError struct {
+	Collisions []Collision
+}
+
+func (e *CollisionError) Error() string {
+	lines := []string{"profile names collide; change naming.template or set naming.collisions:"}
+	for _, c := range e.Collisions {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ResolveNames names the profiles for account roles and resolves names
+// that collide with each other or with existing profiles. existing returns
+// the target of a profile already in the AWS config file. A profile that
+// already signs in to one of the colliding roles keeps its name; the other
+// roles are renamed by the naming.collisions strategy.
+func (c *Config) ResolveNames(roles []NameData, existing func(name string) (Target, bool)) ([]Planned, []Collision, error) {
+	byName := make(map[string][]NameData)
+	var names []string
+	for _, data := range roles {
+		name, err := c.ProfileName(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], data)
+	}
+
+	// Names already taken, so renamed roles don't land on one
+	taken := make(map[string]bool)
+	for _, name := range names {
+		taken[name] = true
+	}
+
+	var planned []Planned
+	var collisions []Collision
+	for _, name := range names {
+		group := byName[name]
+		owner, ownerFound := -1, false
+		existingTarget, exists := existing(name)
+		for i, data := range group {
+			if exists && data.target() == existingTarget {
+				owner, ownerFound = i, true
+			}
+		}
+		if len(group) == 1 && (!exists || ownerFound) {
+			planned = append(planned, Planned{name, group[0]})
+			continue
+		}
+
+		collision := Collision{
+			Name:     name,
+			Roles:    group,
+			Existing: exists && !ownerFound,
+			Renamed:  make(map[Target]string),
+		}
+		for i, data := range group {
+			if i == owner {
+				planned = append(planned, Planned{name, data})
+				continue
+			}
+			if c.collisionStrategy() == FailOnCollision {
+				continue
+			}
+			renamed, ok := c.rename(name, data, func(n string) bool {
+				if taken[n] {
+					return true
+				}
+				t, exists := existing(n)
+				return exists && t != data.target()
+			})
+			if !ok {
+				return nil, nil, fmt.Errorf("can't find a free profile name for %s in %s (%s); change naming.template", data.RoleName, data.AccountID, data.Session)
+			}
+			taken[renamed] = true
+			collision.Renamed[data.target()] = renamed
+			planned = append(planned, Planned{renamed, data})
+		}
+		collisions = append(collisions, collision)
+	}
+
+	if len(collisions) > 0 && c.collisionStrategy() == FailOnCollision {
+		return nil, collisions, &CollisionError{collisions}
+	}
+	sort.Slice(planned, func(i, j int) bool {
+		return planned[i].Name < planned[j].Name
+	})
+	return planned, collisions, nil
+}
+
+func (c *Config) collisionStrategy() string {
+	if c.Naming.Collisions == "" {
+		return SuffixAccountID
+	}
+	return c.Naming.Collisions
+}
+
+// rename applies the collision strategy, then the other strategy too if
+// the name is still taken, e.g. for one account reachable through two
+// sessions
+func (c *Config) rename(name string, data NameData, taken func(string) bool) (string, bool) {
+	suffixed := name + "_" + data.AccountID
+	prefixed := data.Session + "_" + name
+	candidates := []string{suffixed, prefixed}
+	if c.collisionStrategy() == PrefixSession {
+		candidates = []string{prefixed, suffixed}
+	}
+	candidates = append(candidates, data.Session+"_"+suffixed)
+	for _, candidate := range candidates {
+		if !taken(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
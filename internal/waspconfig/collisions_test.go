@@ -0,0 +1,111 @@
+package waspconfig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveNames(t *testing.T) {
+	prod := NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme", RoleName: "Admin"}
+	dup := NameData{Session: "corp", AccountID: "222222222222", AccountName: "Acme", RoleName: "Admin"}
+	other := NameData{Session: "partner", AccountID: "111111111111", AccountName: "Acme", RoleName: "Admin"}
+	dev := NameData{Session: "corp", AccountID: "333333333333", AccountName: "Dev", RoleName: "Admin"}
+
+	existing := map[string]Target{
+		// Hand-written profile pointing somewhere else
+		"Dev_Admin": {Session: "corp", AccountID: "999999999999", RoleName: "Admin"},
+		// Already synced for prod, so prod keeps the name
+		"Acme_Admin": prod.target(),
+	}
+	lookup := func(name string) (Target, bool) {
+		t, ok := existing[name]
+		return t, ok
+	}
+
+	tests := []struct {
+		strategy string
+		want     []Planned
+	}{
+		{
+			strategy: SuffixAccountID,
+			want: []Planned{
+				{"Acme_Admin", prod},
+				{"Acme_Admin_111111111111", other},
+				{"Acme_Admin_222222222222", dup},
+				{"Dev_Admin_333333333333", dev},
+			},
+		},
+		{
+			strategy: PrefixSession,
+			want: []Planned{
+				{"Acme_Admin", prod},
+				{"corp_Acme_Admin", dup},
+				{"corp_Dev_Admin", dev},
+				{"partner_Acme_Admin", other},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			c := &Config{Naming: Naming{Collisions: tt.strategy}}
+			planned, collisions, err := c.ResolveNames([]NameData{prod, dup, other, dev}, lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(planned, tt.want) {
+				t.Errorf("planned = %+v, want %+v", planned, tt.want)
+			}
+			if len(collisions) != 2 {
+				t.Errorf("collisions = %+v, want 2", collisions)
+			}
+
+			// Renamed profiles keep their names on the next sync
+			for _, p := range planned {
+				existing[p.Name] = p.Data.target()
+			}
+			again, _, err := c.ResolveNames([]NameData{prod, dup, other, dev}, lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, tt.want) {
+				t.Errorf("second sync planned = %+v, want %+v", again, tt.want)
+			}
+			for _, p := range planned {
+				if p.Name != "Acme_Admin" {
+					delete(existing, p.Name)
+				}
+			}
+		})
+	}
+
+	t.Run(FailOnCollision, func(t *testing.T) {
+		c := &Config{Naming: Naming{Collisions: FailOnCollision}}
+		_, _, err := c.ResolveNames([]NameData{prod, dup, dev}, lookup)
+		var cerr *CollisionError
+		if !errors.As(err, &cerr) || len(cerr.Collisions) != 2 {
+			t.Fatalf("ResolveNames() = %v, want a CollisionError with 2 collisions", err)
+		}
+	})
+
+	t.Run("same account in two sessions", func(t *testing.T) {
+		c := &Config{}
+		none := func(string) (Target, bool) { return Target{}, false }
+		planned, _, err := c.ResolveNames([]NameData{prod, other}, none)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Planned{{"Acme_Admin_111111111111", prod}, {"partner_Acme_Admin", other}}
+		if !reflect.DeepEqual(planned, want) {
+			t.Errorf("planned = %+v, want %+v", planned, want)
+		}
+	})
+
+	t.Run("no collisions", func(t *testing.T) {
+		c := &Config{}
+		planned, collisions, err := c.ResolveNames([]NameData{prod}, lookup)
+		if err != nil || len(collisions) != 0 || len(planned) != 1 || planned[0].Name != "Acme_Admin" {
+			t.Errorf("ResolveNames() = %+v, %+v, %v", planned, collisions, err)
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package waspconfig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/buzzsurfr/wasp/internal/waspdir"
+	"go.yaml.in/yaml/v3"
+)
+
+// Version is the config file version this wasp reads and writes
+const Version = 1
+
+// DefaultNameTemplate names profiles the way wasp always has
+const DefaultNameTemplate = "{{.AccountName}}_{{.RoleName}}"
+
+// Config is the wasp configuration file, ~/.wasp/config.yaml by default
+type Config struct {
+	// Version is the schema version, Version when written by this wasp
+	Version int `yaml:"version"`
+
+	// Sessions lists the SSO sessions to sync. With none listed, every SSO
+	// session in the AWS config file is synced.
+	Sessions []Session `yaml:"sessions,omitempty"`
+
+	// Naming controls the names of generated profiles
+	Naming Naming `yaml:"naming,omitempty"`
+
+	// Defaults are keys written into every generated profile, e.g. region
+	Defaults map[string]string `yaml:"defaults,omitempty"`
+
+	// Rules set keys on the generated profiles they match, overriding
+	// Defaults
+	Rules []Rule `yaml:"rules,omitempty"`
+
+	// UI holds preferences for the interactive pickers
+	UI UI `yaml:"ui,omitempty"`
+
+	// Chains generate profiles that assume a role from the SSO profiles
+	// they match
+	Chains []Chain `yaml:"chains,omitempty"`
+
+	// Aliases maps short names to profile names
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+
+	// ManifestAliases records the aliases wasp apply set for each team
+	// manifest, by manifest name, so a later apply can remove the ones the
+	// manifest drops
+	ManifestAliases map[string]map[string]string `yaml:"manifest_aliases,omitempty"`
+
+	// Files moves generated profiles out of the AWS config file
+	Files Files `yaml:"files,omitempty"`
+
+	// Kube controls the kubeconfig contexts wasp kube sync writes
+	Kube Kube `yaml:"kube,omitempty"`
+
+	// Prompt controls the segment wasp prompt prints
+	Prompt Prompt `yaml:"prompt,omitempty"`
+
+	// Extra holds keys wasp doesn't know about so they survive a save, e.g.
+	// ones written by a newer wasp. UnknownKeys lists them.
+	Extra map[string]any `yaml:",inline"`
+
+	path string
+	raw  []byte
+	// file is the file as last read or written; Save keeps its comments
+	// and key order
+	file []byte
+}
+
+// Session is an SSO session to sync and which of its roles to sync
+type Session struct {
+	Name    string  `yaml:"name"`
+	Filters Filters `yaml:"filters,omitempty"`
+}
+
+// Naming controls the names of generated profiles
+type Naming struct {
+	// Template is a Go template executed with a NameData
+	Template string `yaml:"template,omitempty"`
+
+	// Collisions is how to rename profiles whose names collide:
+	// suffix-account-id (the default), prefix-session or fail
+	Collisions string `yaml:"collisions,omitempty"`
+}
+
+// NameData is what a naming template can refer to. SSORegion is the SSO
+// session's region; chains assume roles in its partition.
+type NameData struct {
+	Session      string
+	SSORegion    string
+	AccountID    string
+	AccountName  string
+	AccountEmail string
+	RoleName     string
+}
+
+// UI holds preferences for the interactive pickers
+type UI struct {
+	// Tree starts switch and init in the tree view
+	Tree bool `yaml:"tree,omitempty"`
+
+	// Height is how many rows the pickers show
+	Height int `yaml:"height,omitempty"`
+
+	// TagColors colors profiles by tag, overriding DefaultTagColors. Colors
+	// are ANSI 256 color numbers or #rrggbb.
+	TagColors map[string]string `yaml:"tag_colors,omitempty"`
+}
+
+// DefaultTagColors are the colors of the tags that make wasp ask first:
+// red for prod and orange for sensitive
+var DefaultTagColors = map[string]string{
+	TagProd:      "196",
+	TagSensitive: "208",
+}
+
+// TagColor returns the color of the first of tags that has one, or ""
+func (u UI) TagColor(tags []string) string {
+	for _, tag := range tags {
+		if color, ok := u.TagColors[tag]; ok {
+			return color
+		}
+	}
+	for _, tag := range tags {
+		if color, ok := DefaultTagColors[tag]; ok {
+			return color
+		}
+	}
+	return ""
+}
+
+// Files splits the AWS config into a file wasp owns and hand-written files,
+// composed into the file the AWS CLI and SDKs read. Without Managed, wasp
+// writes straight into the AWS config file.
+type Files struct {
+	// Managed is the file wasp writes generated profiles to, e.g.
+	// ~/.aws/config.d/wasp
+	Managed string `yaml:"managed,omitempty"`
+
+	// Sources are hand-written config files, read before Managed
+	Sources []string `yaml:"sources,omitempty"`
+
+	// Compose is the file Sources and Managed are concatenated into,
+	// the AWS config file unless set
+	Compose string `yaml:"compose,omitempty"`
+}
+
+// ManagedPath returns Managed with ~ expanded
+func (f Files) ManagedPath() string {
+	return expandHome(f.Managed)
+}
+
+// SourcePaths returns Sources with ~ expanded
+func (f Files) SourcePaths() []string {
+	var paths []string
+	for _, source := range f.Sources {
+		paths = append(paths, expandHome(source))
+	}
+	return paths
+}
+
+// ComposePath returns Compose with ~ expanded, or def if it isn't set
+func (f Files) ComposePath(def string) string {
+	if f.Compose == "" {
+		return def
+	}
+	return expandHome(f.Compose)
+}
+
+// Kube controls the kubeconfig contexts wasp kube sync writes for EKS
+// clusters
+type Kube struct {
+	// Clusters is the cluster inventory, ~/.wasp/clusters.yaml unless set
+	Clusters string `yaml:"clusters,omitempty"`
+
+	// Kubeconfig is the file contexts are written to, the one kubectl
+	// uses unless set
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+
+	// Context is a Go template for context names, executed with a KubeData
+	Context string `yaml:"context,omitempty"`
+}
+
+// ClustersPath returns Clusters with ~ expanded, or def if it isn't set
+func (k Kube) ClustersPath(def string) string {
+	if k.Clusters == "" {
+		return def
+	}
+	return expandHome(k.Clusters)
+}
+
+// KubeconfigPath returns Kubeconfig with ~ expanded, or def if it isn't set
+func (k Kube) KubeconfigPath(def string) string {
+	if k.Kubeconfig == "" {
+		return def
+	}
+	return expandHome(k.Kubeconfig)
+}
+
+// Prompt controls the shell prompt segment
+type Prompt struct {
+	// Format is a Go template executed with a prompt.Data, prompt.DefaultFormat
+	// unless set. wasp config validate checks it with prompt.Check.
+	Format string `yaml:"format,omitempty"`
+}
+
+// DefaultContextTemplate names kubeconfig contexts after the profile and
+// the cluster
+const DefaultContextTemplate = "{{.Profile}}@{{.Cluster}}"
+
+// KubeData is what context name templates can refer to: the profile's
+// account role, the profile's name and the cluster's name and region
+type KubeData struct {
+	NameData
+	Profile string
+	Cluster string
+	Region  string
+}
+
+// ContextName names the kubeconfig context for a profile and a cluster
+func (c *Config) ContextName(data KubeData) (string, error) {
+	tmpl, err := c.contextTemplate()
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (c *Config) contextTemplate() (*template.Template, error) {
+	text := c.Kube.Context
+	if text == "" {
+		text = DefaultContextTemplate
+	}
+	return newTemplate("kube.context", text)
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// Rows returns how many rows a picker shows, def unless configured
+func (u UI) Rows(def int) int {
+	if u.Height > 0 {
+		return u.Height
+	}
+	return def
+}
+
+// DefaultPath returns where the config file lives unless told otherwise
+func DefaultPath() (string, error) {
+	return waspdir.Path("config.yaml")
+}
+
+// Load reads the config file at path. A missing file is an empty config.
+// Unknown keys are ignored here and reported by Validate.
+func Load(path string) (*Config, error) {
+	c := &Config{Version: Version, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	c.raw = data
+	c.file = data
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Exists reports whether the config was loaded from a file
+func (c *Config) Exists() bool {
+	return c.raw != nil
+}
+
+// Path returns the file the config was loaded from
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Save writes the config back to the file it was loaded from, keeping the
+// comments and key order of what's there. The file is replaced atomically.
+func (c *Config) Save() error {
+	if c.Version == 0 {
+		c.Version = Version
+	}
+	var updated yaml.Node
+	if err := updated.Encode(c); err != nil {
+		return err
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&updated}}
+	var old yaml.Node
+	if err := yaml.Unmarshal(c.file, &old); err == nil && len(old.Content) > 0 {
+		mergeNode(old.Content[0], &updated)
+		doc = &old
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(indentOf(c.file))
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	data := b.Bytes()
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.raw = data
+	c.file = data
+	return nil
+}
+
+// Session returns the settings for an SSO session, or nil if the session
+// isn't listed
+func (c *Config) Session(name string) *Session {
+	for i := range c.Sessions {
+		if c.Sessions[i].Name == name {
+			return &c.Sessions[i]
+		}
+	}
+	return nil
+}
+
+// ProfileName names the profile for an account role
+func (c *Config) ProfileName(data NameData) (string, error) {
+	tmpl, err := c.nameTemplate()
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (c *Config) nameTemplate() (*template.Template, error) {
+	text := c.Naming.Template
+	if text == "" {
+		text = DefaultNameTemplate
+	}
+	return newTemplate("naming.template", text)
+}
+
+// newTemplate parses a template with the functions naming templates can use
+func newTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"lower":   strings.ToLower,
+		"upper":   strings.ToUpper,
+		"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	}).Parse(text)
+}
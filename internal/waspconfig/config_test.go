@@ -0,0 +1,354 @@
+package waspconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadString(t *testing.T, text string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoadMissing(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Exists() {
+		t.Error("Exists() = true for a missing file")
+	}
+	if c.Version != Version {
+		t.Errorf("Version = %d, want %d", c.Version, Version)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	c := loadString(t, "aliases:\n  prod: Acme Production_AdministratorAccess\n")
+	c.Aliases["dev"] = "Acme Dev_ReadOnly"
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(c.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != Version || len(c.Aliases) != 2 {
+		t.Errorf("reloaded config = %+v", c)
+	}
+}
+
+func TestSaveKeepsUnknownKeys(t *testing.T) {
+	c := loadString(t, "theme: dark\nui:\n  tree: true\naliases:\n  prod: Acme Production_AdministratorAccess\n")
+	c.Aliases["dev"] = "Acme Dev_ReadOnly"
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(c.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"theme: dark", "dev: Acme Dev_ReadOnly", "prod: Acme Production_AdministratorAccess"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved config missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestSaveKeepsComments(t *testing.T) {
+	text := `# Team defaults
+aliases:
+  prod: Acme Production_AdministratorAccess # the one we page on
+version: 1
+sessions:
+  - name: corp
+    filters:
+      exclude:
+        - account: "sandbox-*" # nobody uses these
+`
+	c := loadString(t, text)
+	c.Aliases["dev"] = "Acme Dev_ReadOnly"
+	c.UI.Tree = true
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(c.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Team defaults
+aliases:
+  prod: Acme Production_AdministratorAccess # the one we page on
+  dev: Acme Dev_ReadOnly
+version: 1
+sessions:
+  - name: corp
+    filters:
+      exclude:
+        - account: "sandbox-*" # nobody uses these
+ui:
+  tree: true
+`
+	if string(data) != want {
+		t.Errorf("saved config =\n%s\nwant\n%s", data, want)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(c.Path())); len(entries) != 1 {
+		t.Errorf("Save left %d files behind, want 1", len(entries))
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	c := loadString(t, "version: 1\ntheme: dark\nui:\n  tree: true\n")
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want unknown top-level keys left alone", err)
+	}
+	if got := c.UnknownKeys(); !reflect.DeepEqual(got, []string{"line 2: theme"}) {
+		t.Errorf("UnknownKeys() = %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "valid",
+			text: `version: 1
+sessions:
+  - name: corp
+    filters:
+      exclude:
+        - account: "sandbox-*"
+naming:
+  template: "{{.Session}}-{{lower .AccountName}}-{{.RoleName}}"
+defaults:
+  region: eu-west-1
+ui:
+  tree: true
+`,
+		},
+		{
+			name: "unknown key",
+			text: "version: 1\nui:\n  colour: blue\n",
+			want: []string{"line 3: field colour not found"},
+		},
+		{
+			name: "newer version",
+			text: "version: 2\n",
+			want: []string{"version: 2 is newer"},
+		},
+		{
+			name: "sessions",
+			text: `sessions:
+  - name: corp
+  - name: corp
+    filters:
+      include:
+        - role: "[Admin"
+        - {}
+        - email: "re:(["
+  - filters: {}
+`,
+			want: []string{
+				`sessions[1].name: session "corp" is listed more than once`,
+				`sessions[1].filters.include[0].role: bad pattern "[Admin"`,
+				"sessions[1].filters.include[1]: set at least one of",
+				`sessions[1].filters.include[2].email: bad pattern "re:(["`,
+				"sessions[2].name: required",
+			},
+		},
+		{
+			name: "template",
+			text: "naming:\n  template: \"{{.Account}}\"\n  collisions: rename\n",
+			want: []string{"naming.template:", `naming.collisions: "rename" is not one of`},
+		},
+		{
+			name: "tags",
+			text: "rules:\n  - account: x\n    tags: [\"bad tag\"]\nui:\n  tag_colors:\n    prod: red\n",
+			want: []string{"rules[0].tags[0]:", "ui.tag_colors.prod:"},
+		},
+		{
+			name: "kube",
+			text: "kube:\n  context: \"{{.ClusterName}}\"\n",
+			want: []string{"kube.context:"},
+		},
+		{
+			name: "rules",
+			text: `rules:
+  - set:
+      region: eu-west-1
+  - role: "re:("
+  - account: "*-eu-*"
+    set:
+      sso_account_id: "1"
+`,
+			want: []string{
+				"rules[0]: set at least one of",
+				`rules[1].role: bad pattern "re:("`,
+				"rules[1].set: no keys to set",
+				"rules[2].set.sso_account_id: set by wasp sync",
+			},
+		},
+		{
+			name: "defaults",
+			text: "defaults:\n  sso_role_name: Admin\n",
+			want: []string{"defaults.sso_role_name: set by wasp sync"},
+		},
+		{
+			name: "files",
+			text: `files:
+  managed: ~/.aws/config.d/wasp
+  sources:
+    - ~/.aws/config.d/wasp
+    - ~/.aws/config
+  compose: ~/.aws/config
+`,
+			want: []string{
+				"files.sources[0]: the managed file can't also be a source",
+				"files.sources[1]: the composed file can't also be a source",
+			},
+		},
+		{
+			name: "chains",
+			text: `chains:
+  - role: Admin
+  - assume: Deploy
+    set:
+      role_arn: x
+  - role: Admin
+    assume: "arn:aws:s3:::bucket"
+`,
+			want: []string{
+				"chains[0].assume: required",
+				"chains[1]: set at least one of",
+				"chains[1].set.role_arn: set by wasp sync",
+				`chains[2].assume: "arn:aws:s3:::bucket" isn't an IAM role ARN`,
+			},
+		},
+		{
+			name: "files without managed",
+			text: "files:\n  compose: /tmp/aws-config\n",
+			want: []string{"files.managed: required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadString(t, tt.text).Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() = %v, want a *ValidationError", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Fatalf("Problems = %q, want %d", verr.Problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(verr.Problems[i], want) {
+					t.Errorf("Problems[%d] = %q, want %q", i, verr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	c := loadString(t, "sessions:\n  - name: corp\n")
+
+	if err := c.Set("ui.tree", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.UI.Tree {
+		t.Error("ui.tree not set")
+	}
+	if err := c.Set("naming.template", "{{.Session}}-{{.AccountName}}"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Naming.Template != "{{.Session}}-{{.AccountName}}" {
+		t.Errorf("naming.template = %q", c.Naming.Template)
+	}
+	if err := c.Set("defaults.region", "eu-west-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("sessions.1.name", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Get("sessions.1.name"); err != nil || got != "dev" {
+		t.Errorf("Get(sessions.1.name) = %v, %v", got, err)
+	}
+	if got, err := c.Get("defaults.region"); err != nil || got != "eu-west-1" {
+		t.Errorf("Get(defaults.region) = %v, %v", got, err)
+	}
+	if err := c.Set("defaults.region", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("defaults.region"); err == nil {
+		t.Error("defaults.region still set after removing it")
+	}
+
+	// Settings outside the schema are refused and leave the config alone
+	if err := c.Set("ui.colour", "blue"); err == nil {
+		t.Error("Set(ui.colour) succeeded")
+	}
+	if err := c.Set("ui.height", "-1"); err == nil {
+		t.Error("Set(ui.height, -1) succeeded")
+	}
+	if c.UI.Height != 0 || len(c.Sessions) != 2 {
+		t.Errorf("config changed by a failed Set: %+v", c)
+	}
+}
+
+func TestProfileName(t *testing.T) {
+	data := NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme Prod", RoleName: "ReadOnly"}
+
+	c := &Config{}
+	if got, _ := c.ProfileName(data); got != "Acme Prod_ReadOnly" {
+		t.Errorf("default ProfileName = %q", got)
+	}
+	c.Naming.Template = `{{.Session}}-{{replace " " "-" .AccountName | lower}}-{{.RoleName}}`
+	if got, _ := c.ProfileName(data); got != "corp-acme-prod-ReadOnly" {
+		t.Errorf("ProfileName = %q", got)
+	}
+}
+
+func TestContextName(t *testing.T) {
+	data := KubeData{
+		NameData: NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme Prod", RoleName: "ReadOnly"},
+		Profile:  "Acme Prod_ReadOnly",
+		Cluster:  "main",
+		Region:   "eu-west-1",
+	}
+
+	c := &Config{}
+	if got, _ := c.ContextName(data); got != "Acme Prod_ReadOnly@main" {
+		t.Errorf("default ContextName = %q", got)
+	}
+	c.Kube.Context = `{{.Cluster}}-{{.Region}}-{{lower .RoleName}}`
+	if got, _ := c.ContextName(data); got != "main-eu-west-1-readonly" {
+		t.Errorf("ContextName = %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+package waspconfig
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Filters decide which account roles of a session get profiles. A role is
+// synced when its account and role are on the allowlists (if there are
+// any), it matches no exclude rule, and it matches an include rule (if
+// there are any).
+type Filters struct {
+	// Accounts allowlists accounts by ID or name
+	Accounts []string `yaml:"accounts,omitempty"`
+
+	// Roles allowlists role names
+	Roles []string `yaml:"roles,omitempty"`
+
+	Include []Match `yaml:"include,omitempty"`
+	Exclude []Match `yaml:"exclude,omitempty"`
+}
+
+func (f Filters) empty() bool {
+	return len(f.Accounts) == 0 && len(f.Roles) == 0 && len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match is a filter rule. Each field is a glob, or a regular expression
+// when it starts with "re:", and every field that is set must match.
+type Match struct {
+	Account   string `yaml:"account,omitempty"`
+	AccountID string `yaml:"account_id,omitempty"`
+	Email     string `yaml:"email,omitempty"`
+	Role      string `yaml:"role,omitempty"`
+}
+
+// Decision is whether a session's filters let an account role through, and
+// why
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+// Allows reports whether the session's filters let an account role through
+func (s *Session) Allows(data NameData) bool {
+	return s.Decide(data).Allowed
+}
+
+// Decide applies the session's filters to an account role. A nil session
+// (one not listed in the config) allows everything.
+func (s *Session) Decide(data NameData) Decision {
+	if s == nil || s.Filters.empty() {
+		return Decision{true, "no filters"}
+	}
+	f := s.Filters
+	if len(f.Accounts) > 0 && !slices.Contains(f.Accounts, data.AccountID) && !slices.Contains(f.Accounts, data.AccountName) {
+		return Decision{false, "account not in filters.accounts"}
+	}
+	if len(f.Roles) > 0 && !slices.Contains(f.Roles, data.RoleName) {
+		return Decision{false, "role not in filters.roles"}
+	}
+	for i, m := range f.Exclude {
+		if m.Matches(data) {
+			return Decision{false, fmt.Sprintf("excluded by filters.exclude[%d] (%s)", i, m)}
+		}
+	}
+	for i, m := range f.Include {
+		if m.Matches(data) {
+			return Decision{true, fmt.Sprintf("included by filters.include[%d] (%s)", i, m)}
+		}
+	}
+	if len(f.Include) > 0 {
+		return Decision{false, "matched no filters.include rule"}
+	}
+	if len(f.Accounts) > 0 || len(f.Roles) > 0 {
+		return Decision{true, "allowlisted"}
+	}
+	return Decision{true, "not excluded"}
+}
+
+// Matches reports whether every field set in m matches the account role
+func (m Match) Matches(data NameData) bool {
+	for _, f := range m.fields(data) {
+		if ok, _ := matchPattern(f.pattern, f.value); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Match) String() string {
+	var parts []string
+	for _, f := range m.fields(NameData{}) {
+		parts = append(parts, fmt.Sprintf("%s %q", f.name, f.pattern))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type matchField struct {
+	name, pattern, value string
+}
+
+// fields returns the fields set in m with the values they're matched against
+func (m Match) fields(data NameData) []matchField {
+	var fields []matchField
+	for _, f := range []matchField{
+		{"account", m.Account, data.AccountName},
+		{"account_id", m.AccountID, data.AccountID},
+		{"email", m.Email, data.AccountEmail},
+		{"role", m.Role, data.RoleName},
+	} {
+		if f.pattern != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// regexps caches compiled "re:" patterns, since every rule is tried against
+// every account role
+var regexps sync.Map
+
+// matchPattern matches a value against a glob or a "re:" regular expression
+func matchPattern(pattern, value string) (bool, error) {
+	expr, ok := strings.CutPrefix(pattern, "re:")
+	if !ok {
+		return path.Match(pattern, value)
+	}
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp).MatchString(value), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, err
+	}
+	regexps.Store(expr, re)
+	return re.MatchString(value), nil
+}
@@ -0,0 +1,52 @@
+package waspconfig
+
+import (
+	"testing"
+)
+
+func TestDecide(t *testing.T) {
+	s := &Session{Filters: Filters{
+		Include: []Match{{Account: "Acme *"}, {AccountID: "re:^2222"}},
+		Exclude: []Match{{Account: "Acme Sandbox*"}, {Role: "Billing"}},
+	}}
+	tests := []struct {
+		account, id, role string
+		want              Decision
+	}{
+		{"Acme Prod", "111111111111", "ReadOnly", Decision{true, `included by filters.include[0] (account "Acme *")`}},
+		{"Other", "222222222222", "ReadOnly", Decision{true, `included by filters.include[1] (account_id "re:^2222")`}},
+		{"Acme Prod", "111111111111", "Billing", Decision{false, `excluded by filters.exclude[1] (role "Billing")`}},
+		{"Acme Sandbox 12", "333333333333", "ReadOnly", Decision{false, `excluded by filters.exclude[0] (account "Acme Sandbox*")`}},
+		{"Other", "444444444444", "ReadOnly", Decision{false, "matched no filters.include rule"}},
+	}
+	for _, tt := range tests {
+		got := s.Decide(NameData{AccountName: tt.account, AccountID: tt.id, RoleName: tt.role})
+		if got != tt.want {
+			t.Errorf("Decide(%s, %s) = %+v, want %+v", tt.account, tt.role, got, tt.want)
+		}
+	}
+	if !(*Session)(nil).Allows(NameData{}) {
+		t.Error("a session without settings should allow everything")
+	}
+}
+
+func TestDecideAllowlist(t *testing.T) {
+	s := &Session{Filters: Filters{
+		Accounts: []string{"Acme Prod", "222222222222"},
+		Roles:    []string{"ReadOnly"},
+	}}
+	tests := []struct {
+		account, id, role string
+		want              bool
+	}{
+		{"Acme Prod", "111111111111", "ReadOnly", true},
+		{"Acme Dev", "222222222222", "ReadOnly", true},
+		{"Acme Prod", "111111111111", "AdministratorAccess", false},
+		{"Acme Sandbox", "333333333333", "ReadOnly", false},
+	}
+	for _, tt := range tests {
+		if got := s.Allows(NameData{AccountName: tt.account, AccountID: tt.id, RoleName: tt.role}); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tt.account, tt.role, got, tt.want)
+		}
+	}
+}
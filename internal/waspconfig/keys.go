@@ -0,0 +1,218 @@
+package waspconfig
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Get returns the value at a dotted key such as "naming.template" or
+// "sessions.0.name". An empty key returns the whole config.
+func (c *Config) Get(key string) (any, error) {
+	tree, err := c.tree()
+	if err != nil {
+		return nil, err
+	}
+	var v any = tree
+	for _, part := range splitKey(key) {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[part]; !ok {
+				return nil, fmt.Errorf("%s is not set", key)
+			}
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("%s: no item %s", key, part)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("%s is not set", key)
+		}
+	}
+	return v, nil
+}
+
+// Set changes the value at a dotted key. The value is parsed as YAML if it
+// can be, so "true" and "10" become a bool and a number; an empty value
+// removes the key. The result must decode into the schema and validate.
+func (c *Config) Set(key, value string) error {
+	parts := splitKey(key)
+	if len(parts) == 0 {
+		return fmt.Errorf("no key given")
+	}
+	// Anything that isn't YAML, like a naming template, is a plain string
+	var v any
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+
+	tree, err := c.tree()
+	if err != nil {
+		return err
+	}
+	updated, err := setIn(tree, parts, v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	data, err := yaml.Marshal(updated)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	next := Config{path: c.path, raw: data, file: c.file}
+	if err := dec.Decode(&next); err != nil {
+		return fmt.Errorf("%s: not a valid setting: %w", key, err)
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	*c = next
+	return nil
+}
+
+// tree returns the config as plain maps and slices
+func (c *Config) tree() (map[string]any, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(map[string]any)
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// setIn sets (or with a nil value, removes) the value at parts, creating
+// maps and lists along the way. A list index equal to the list's length appends.
+func setIn(node any, parts []string, v any) (any, error) {
+	if len(parts) == 0 {
+		return v, nil
+	}
+	part, rest := parts[0], parts[1:]
+	switch n := node.(type) {
+	case nil:
+		if v == nil {
+			return nil, nil
+		}
+		if _, err := strconv.Atoi(part); err == nil {
+			return setIn([]any{}, parts, v)
+		}
+		return setIn(map[string]any{}, parts, v)
+	case map[string]any:
+		child, err := setIn(n[part], rest, v)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			delete(n, part)
+		} else {
+			n[part] = child
+		}
+		return n, nil
+	case []any:
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 0 || i > len(n) {
+			return nil, fmt.Errorf("no item %s", part)
+		}
+		if i == len(n) {
+			n = append(n, nil)
+		}
+		child, err := setIn(n[i], rest, v)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			return append(n[:i], n[i+1:]...), nil
+		}
+		n[i] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%s is inside a value that isn't a map or list", part)
+	}
+}
+
+// mergeNode makes old hold the values of updated while keeping its
+// comments, key order and the style of values that didn't change. Keys and
+// items updated doesn't have are removed; new keys go last.
+func mergeNode(old, updated *yaml.Node) {
+	if old.Kind != updated.Kind || old.Tag != updated.Tag {
+		head, line, foot := old.HeadComment, old.LineComment, old.FootComment
+		*old = *updated
+		old.HeadComment, old.LineComment, old.FootComment = head, line, foot
+		return
+	}
+	switch old.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			if j := mappingIndex(updated, old.Content[i].Value); j >= 0 {
+				mergeNode(old.Content[i+1], updated.Content[j+1])
+				content = append(content, old.Content[i], old.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			if mappingIndex(old, updated.Content[i].Value) < 0 {
+				content = append(content, updated.Content[i], updated.Content[i+1])
+			}
+		}
+		old.Content = content
+	case yaml.SequenceNode:
+		for i, item := range updated.Content {
+			if i < len(old.Content) {
+				mergeNode(old.Content[i], item)
+			} else {
+				old.Content = append(old.Content, item)
+			}
+		}
+		old.Content = old.Content[:len(updated.Content)]
+	case yaml.ScalarNode:
+		if old.Value != updated.Value {
+			old.Value, old.Style = updated.Value, updated.Style
+		}
+	}
+}
+
+// mappingIndex returns the index of a key in a mapping node's content, or
+// -1 if it isn't there
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// indentOf guesses the indentation a YAML file uses, 4 if it has none
+func indentOf(data []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		n := len(line) - len(trimmed)
+		if n == 0 || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent == 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent < 2 {
+		return 4
+	}
+	return indent
+}
+
+func splitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
@@ -0,0 +1,87 @@
+package waspconfig
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Rule sets keys on the generated profiles it matches, e.g. a region for
+// accounts named "*-eu-*", and tags the profiles it matches, e.g. prod.
+// Session is a pattern like the Match fields.
+type Rule struct {
+	Session string `yaml:"session,omitempty"`
+	Match   `yaml:",inline"`
+	Set     map[string]string `yaml:"set,omitempty"`
+	Tags    []string          `yaml:"tags,omitempty"`
+}
+
+// Tags that make wasp ask before switching to a profile or running a
+// command with it
+const (
+	TagProd      = "prod"
+	TagSensitive = "sensitive"
+)
+
+// Sensitive reports whether tags include one that makes wasp ask first
+func Sensitive(tags []string) bool {
+	return slices.Contains(tags, TagProd) || slices.Contains(tags, TagSensitive)
+}
+
+// Matches reports whether the rule applies to an account role
+func (r Rule) Matches(data NameData) bool {
+	if r.Session != "" {
+		if ok, _ := matchPattern(r.Session, data.Session); !ok {
+			return false
+		}
+	}
+	return r.Match.Matches(data)
+}
+
+// Tags returns the tags of every rule matching an account role, sorted
+func (c *Config) Tags(data NameData) []string {
+	var tags []string
+	for _, r := range c.Rules {
+		if r.Matches(data) {
+			tags = append(tags, r.Tags...)
+		}
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// Setting is a key for a generated profile and where its value came from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Settings returns the keys to write into the profile for an account role,
+// sorted by key. Defaults come first and then every matching rule in
+// order, so a later rule overrides an earlier one and any rule overrides
+// the defaults. Keys copied from the [default] profile when a section is
+// created rank below all of them.
+func (c *Config) Settings(data NameData) []Setting {
+	settings := make(map[string]Setting)
+	for key, value := range c.Defaults {
+		settings[key] = Setting{key, value, "defaults"}
+	}
+	for i, r := range c.Rules {
+		if !r.Matches(data) {
+			continue
+		}
+		for key, value := range r.Set {
+			settings[key] = Setting{key, value, fmt.Sprintf("rules[%d]", i)}
+		}
+	}
+
+	var list []Setting
+	for _, s := range settings {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
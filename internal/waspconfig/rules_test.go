@@ -0,0 +1,88 @@
+package waspconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	c := loadString(t, `defaults:
+  region: us-east-1
+  output: json
+rules:
+  - account: "*-eu-*"
+    set:
+      region: eu-west-1
+  - role: "ReadOnly*"
+    set:
+      output: table
+  - session: dev
+    account: "*-eu-*"
+    set:
+      region: eu-central-1
+`)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data NameData
+		want []Setting
+	}{
+		{
+			name: "defaults only",
+			data: NameData{Session: "corp", AccountName: "acme-us-prod", RoleName: "Admin"},
+			want: []Setting{{"output", "json", "defaults"}, {"region", "us-east-1", "defaults"}},
+		},
+		{
+			name: "rules override defaults",
+			data: NameData{Session: "corp", AccountName: "acme-eu-prod", RoleName: "ReadOnlyAccess"},
+			want: []Setting{{"output", "table", "rules[1]"}, {"region", "eu-west-1", "rules[0]"}},
+		},
+		{
+			name: "later rules override earlier ones",
+			data: NameData{Session: "dev", AccountName: "acme-eu-dev", RoleName: "Admin"},
+			want: []Setting{{"output", "json", "defaults"}, {"region", "eu-central-1", "rules[2]"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Settings(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Settings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	c := loadString(t, `rules:
+  - account: "* Production"
+    tags: [prod]
+  - role: "Admin*"
+    tags: [admin, prod]
+ui:
+  tag_colors:
+    admin: "33"
+`)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tags := c.Tags(NameData{AccountName: "Acme Production", RoleName: "AdministratorAccess"})
+	if want := []string{"admin", "prod"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Tags() = %v, want %v", tags, want)
+	}
+	if !Sensitive(tags) {
+		t.Errorf("Sensitive(%v) = false", tags)
+	}
+	if got := c.UI.TagColor(tags); got != "33" {
+		t.Errorf("TagColor(%v) = %q, want 33", tags, got)
+	}
+	if got := c.UI.TagColor([]string{"prod"}); got != "196" {
+		t.Errorf("TagColor(prod) = %q, want the default 196", got)
+	}
+	if tags := c.Tags(NameData{AccountName: "Acme Sandbox", RoleName: "ReadOnly"}); len(tags) != 0 || Sensitive(tags) {
+		t.Errorf("Tags() = %v, want none", tags)
+	}
+}
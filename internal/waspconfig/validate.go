@@ -0,0 +1,292 @@
+package waspconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ValidationError lists everything wrong with a config file
+type ValidationError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s is invalid:\n  %s", e.Path, strings.Join(e.Problems, "\n  "))
+}
+
+// generatedKeys are profile keys sync writes itself, so defaults can't set them
+var generatedKeys = map[string]bool{
+	"sso_session":        true,
+	"sso_account_id":     true,
+	"sso_role_name":      true,
+	"wasp_account_name":  true,
+	"wasp_account_email": true,
+	"wasp_alias_for":     true,
+	"wasp_managed":       true,
+	"wasp_keys":          true,
+	"role_arn":           true,
+	"source_profile":     true,
+}
+
+// Validate checks the config for unknown keys and values wasp can't use,
+// returning a *ValidationError describing all of them
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Unknown keys are usually typos, so point at the line
+	if len(c.raw) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(c.raw))
+		dec.KnownFields(true)
+		var strict Config
+		var typeErr *yaml.TypeError
+		if err := dec.Decode(&strict); errors.As(err, &typeErr) {
+			problems = append(problems, typeErr.Errors...)
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			add("%v", err)
+		}
+	}
+	if c.Version > Version {
+		add("version: %d is newer than this wasp understands (%d); upgrade wasp", c.Version, Version)
+	} else if c.Version < 0 {
+		add("version: must be %d", Version)
+	}
+
+	seen := make(map[string]bool)
+	for i, s := range c.Sessions {
+		at := fmt.Sprintf("sessions[%d]", i)
+		if s.Name == "" {
+			add("%s.name: required", at)
+		} else if seen[s.Name] {
+			add("%s.name: session %q is listed more than once", at, s.Name)
+		}
+		seen[s.Name] = true
+		for _, rules := range []struct {
+			kind  string
+			rules []Match
+		}{{"include", s.Filters.Include}, {"exclude", s.Filters.Exclude}} {
+			for j, m := range rules.rules {
+				ruleAt := fmt.Sprintf("%s.filters.%s[%d]", at, rules.kind, j)
+				fields := m.fields(NameData{})
+				if len(fields) == 0 {
+					add("%s: set at least one of account, account_id, email or role", ruleAt)
+				}
+				for _, f := range fields {
+					if _, err := matchPattern(f.pattern, ""); err != nil {
+						add("%s.%s: bad pattern %q: %v", ruleAt, f.name, f.pattern, err)
+					}
+				}
+			}
+		}
+		for _, list := range []struct {
+			key     string
+			entries []string
+		}{{"accounts", s.Filters.Accounts}, {"roles", s.Filters.Roles}} {
+			for j, entry := range list.entries {
+				if entry == "" {
+					add("%s.filters.%s[%d]: empty entry", at, list.key, j)
+				}
+			}
+		}
+	}
+
+	if tmpl, err := c.nameTemplate(); err != nil {
+		add("naming.template: %v", err)
+	} else {
+		var b bytes.Buffer
+		sample := NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme", AccountEmail: "aws@example.com", RoleName: "ReadOnly"}
+		if err := tmpl.Execute(&b, sample); err != nil {
+			add("naming.template: %v", err)
+		} else if name := b.String(); strings.TrimSpace(name) == "" {
+			add("naming.template: produces an empty profile name")
+		} else if strings.ContainsAny(name, "[]\n") {
+			add("naming.template: profile names can't contain brackets or newlines (got %q)", name)
+		}
+	}
+
+	if c.Naming.Collisions != "" && !slices.Contains(collisionStrategies, c.Naming.Collisions) {
+		add("naming.collisions: %q is not one of %s", c.Naming.Collisions, strings.Join(collisionStrategies, ", "))
+	}
+
+	for _, key := range sortedKeys(c.Defaults) {
+		if generatedKeys[key] {
+			add("defaults.%s: set by wasp sync and can't be a default", key)
+		}
+	}
+
+	for i, r := range c.Rules {
+		at := fmt.Sprintf("rules[%d]", i)
+		fields := r.fields(NameData{})
+		if r.Session != "" {
+			fields = append(fields, matchField{"session", r.Session, ""})
+		}
+		if len(fields) == 0 {
+			add("%s: set at least one of session, account, account_id, email or role; use defaults for keys every profile gets", at)
+		}
+		for _, f := range fields {
+			if _, err := matchPattern(f.pattern, ""); err != nil {
+				add("%s.%s: bad pattern %q: %v", at, f.name, f.pattern, err)
+			}
+		}
+		if len(r.Set) == 0 && len(r.Tags) == 0 {
+			add("%s.set: no keys to set or tags to add", at)
+		}
+		for j, tag := range r.Tags {
+			if !tagPattern.MatchString(tag) {
+				add("%s.tags[%d]: %q must be letters, digits, - and _", at, j, tag)
+			}
+		}
+		for _, key := range sortedKeys(r.Set) {
+			if generatedKeys[key] {
+				add("%s.set.%s: set by wasp sync and can't be set by a rule", at, key)
+			}
+		}
+	}
+
+	for i, ch := range c.Chains {
+		at := fmt.Sprintf("chains[%d]", i)
+		fields := ch.fields(NameData{})
+		if ch.Session != "" {
+			fields = append(fields, matchField{"session", ch.Session, ""})
+		}
+		if len(fields) == 0 {
+			add("%s: set at least one of session, account, account_id, email or role", at)
+		}
+		for _, f := range fields {
+			if _, err := matchPattern(f.pattern, ""); err != nil {
+				add("%s.%s: bad pattern %q: %v", at, f.name, f.pattern, err)
+			}
+		}
+		if ch.Assume == "" {
+			add("%s.assume: required", at)
+		} else {
+			sample := Planned{"Acme_ReadOnly", NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme", AccountEmail: "aws@example.com", RoleName: "ReadOnly"}}
+			if p, err := ch.profile(at, sample); err != nil {
+				add("%v", err)
+			} else if strings.TrimSpace(p.Name) == "" {
+				add("%s.name: produces an empty profile name", at)
+			} else if strings.ContainsAny(p.Name, "[]\n") {
+				add("%s.name: profile names can't contain brackets or newlines (got %q)", at, p.Name)
+			}
+		}
+		for _, key := range sortedKeys(ch.Set) {
+			if generatedKeys[key] {
+				add("%s.set.%s: set by wasp sync and can't be set by a chain", at, key)
+			}
+		}
+	}
+
+	if c.UI.Height < 0 {
+		add("ui.height: must not be negative")
+	}
+	for _, tag := range sortedKeys(c.UI.TagColors) {
+		if !colorPattern.MatchString(c.UI.TagColors[tag]) {
+			add("ui.tag_colors.%s: %q isn't an ANSI color number or #rrggbb", tag, c.UI.TagColors[tag])
+		}
+	}
+
+	for _, alias := range sortedKeys(c.Aliases) {
+		if c.Aliases[alias] == "" {
+			add("aliases.%s: missing profile name", alias)
+		}
+	}
+
+	if c.Files.Managed == "" && (len(c.Files.Sources) > 0 || c.Files.Compose != "") {
+		add("files.managed: required when files.sources or files.compose is set")
+	}
+	for i, source := range c.Files.SourcePaths() {
+		switch {
+		case source == "":
+			add("files.sources[%d]: empty entry", i)
+		case source == c.Files.ManagedPath():
+			add("files.sources[%d]: the managed file can't also be a source", i)
+		case c.Files.Managed != "" && source == c.Files.ComposePath(""):
+			add("files.sources[%d]: the composed file can't also be a source", i)
+		}
+	}
+	if c.Files.Managed != "" && c.Files.ManagedPath() == c.Files.ComposePath("") {
+		add("files.compose: the managed file can't also be the composed file")
+	}
+
+	if tmpl, err := c.contextTemplate(); err != nil {
+		add("kube.context: %v", err)
+	} else {
+		var b bytes.Buffer
+		sample := KubeData{
+			NameData: NameData{Session: "corp", AccountID: "111111111111", AccountName: "Acme", AccountEmail: "aws@example.com", RoleName: "ReadOnly"},
+			Profile:  "Acme_ReadOnly",
+			Cluster:  "prod",
+			Region:   "us-east-1",
+		}
+		if err := tmpl.Execute(&b, sample); err != nil {
+			add("kube.context: %v", err)
+		} else if strings.TrimSpace(b.String()) == "" {
+			add("kube.context: produces an empty context name")
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool {
+			// Keep line-numbered decode errors first, in file order
+			return strings.HasPrefix(problems[i], "line ") && !strings.HasPrefix(problems[j], "line ")
+		})
+		return &ValidationError{Path: c.path, Problems: problems}
+	}
+	return nil
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	colorPattern = regexp.MustCompile(`^([0-9]{1,3}|#[0-9A-Fa-f]{6})$`)
+)
+
+// UnknownKeys describes the top-level keys this wasp doesn't know, such as
+// ones a newer wasp wrote. They're kept in Extra, so they aren't problems
+// Validate reports.
+func (c *Config) UnknownKeys() []string {
+	var unknown []string
+	lines := topLevelLines(c.raw)
+	for _, key := range slices.Sorted(maps.Keys(c.Extra)) {
+		if line, ok := lines[key]; ok {
+			unknown = append(unknown, fmt.Sprintf("line %d: %s", line, key))
+		} else {
+			unknown = append(unknown, key)
+		}
+	}
+	return unknown
+}
+
+// topLevelLines returns the line of every top-level key in a document
+func topLevelLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return lines
+	}
+	m := doc.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		lines[m.Content[i].Value] = m.Content[i].Line
+	}
+	return lines
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
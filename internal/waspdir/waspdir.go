@@ -0,0 +1,59 @@
+package waspdir
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory wasp keeps its own files in. It defaults to
+// ~/.wasp and can be moved with the WASP_HOME environment variable.
+func Dir() (string, error) {
+	if dir := os.Getenv("WASP_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".wasp"), nil
+}
+
+// Path returns a path inside the wasp directory
+func Path(elem ...string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// SessionCacheDir returns the directory wasp caches data for an SSO session
+// in. Everything in it is derived from the session and safe to delete.
+// Session names come from the AWS config file, so they're escaped to stay
+// a single directory under cache/sessions.
+func SessionCacheDir(session string) (string, error) {
+	name := url.PathEscape(session)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid SSO session name %q", session)
+	}
+	return Path("cache", "sessions", name)
+}
+
+// RemoveSessionCache deletes the cache directory of an SSO session. It
+// refuses to remove anything that isn't directly inside cache/sessions.
+func RemoveSessionCache(session string) error {
+	dir, err := SessionCacheDir(session)
+	if err != nil {
+		return err
+	}
+	root, err := Path("cache", "sessions")
+	if err != nil {
+		return err
+	}
+	if filepath.Dir(dir) != root {
+		return &os.PathError{Op: "remove", Path: dir, Err: os.ErrPermission}
+	}
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,64 @@
+package waspdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionCacheDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WASP_HOME", home)
+	sessions := filepath.Join(home, "cache", "sessions")
+
+	tests := []struct {
+		session string
+		want    string
+	}{
+		{"corp", filepath.Join(sessions, "corp")},
+		{"../../../victim", filepath.Join(sessions, "..%2F..%2F..%2Fvictim")},
+		{`..\victim`, filepath.Join(sessions, "..%5Cvictim")},
+	}
+	for _, tt := range tests {
+		got, err := SessionCacheDir(tt.session)
+		if err != nil {
+			t.Errorf("SessionCacheDir(%q): %v", tt.session, err)
+		} else if got != tt.want {
+			t.Errorf("SessionCacheDir(%q) = %s, want %s", tt.session, got, tt.want)
+		}
+	}
+	for _, session := range []string{"", ".", ".."} {
+		if got, err := SessionCacheDir(session); err == nil {
+			t.Errorf("SessionCacheDir(%q) = %s, want an error", session, got)
+		}
+	}
+}
+
+func TestRemoveSessionCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("WASP_HOME", home)
+	victim := filepath.Join(home, "victim")
+	if err := os.MkdirAll(victim, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := SessionCacheDir("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveSessionCache("corp"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("%s still exists", dir)
+	}
+	for _, session := range []string{"../../victim", ".."} {
+		RemoveSessionCache(session)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("%s was removed: %v", victim, err)
+	}
+}